@@ -0,0 +1,305 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+	"google.golang.org/grpc"
+)
+
+const streamAccountStatuses = "account_statuses"
+
+// accountEventFields maps the protocol events relating to accounts to the payload field holding
+// the account's address. FlowToken events are added for the client's network.
+var accountEventFields = map[string]string{
+	"flow.AccountCreated":         "address",
+	"flow.AccountKeyAdded":        "address",
+	"flow.AccountKeyRemoved":      "address",
+	"flow.AccountContractAdded":   "address",
+	"flow.AccountContractUpdated": "address",
+	"flow.AccountContractRemoved": "address",
+}
+
+// flowTokenAddresses are the addresses of the FlowToken contract on each network.
+var flowTokenAddresses = map[flow.ChainID]string{
+	flow.Mainnet:    "1654653399040a61",
+	flow.Testnet:    "7e60df042a9c0868",
+	flow.Emulator:   "0ae53cb6e3f42a79",
+	flow.Localnet:   "0ae53cb6e3f42a79",
+	flow.Benchnet:   "0ae53cb6e3f42a79",
+	flow.BftTestnet: "0ae53cb6e3f42a79",
+}
+
+type AccountStatusConfig struct {
+	// IncludeRegisters includes the registers modified in each account. This requires
+	// converting the block's trie updates.
+	IncludeRegisters bool
+
+	// FlowTokenAddress is the address of the FlowToken contract, used to find deposits and
+	// withdrawals. Defaults to the address for the client's network.
+	FlowTokenAddress string
+}
+
+// AccountRegister is a register modified in a block.
+type AccountRegister struct {
+	ID    flow.RegisterID
+	Value []byte
+}
+
+// AccountStatus is the activity of an account in a block.
+type AccountStatus struct {
+	Address   flow.Address
+	Events    []flow.Event
+	Registers []AccountRegister
+}
+
+type AccountStatusesResponse struct {
+	BlockID flow.Identifier
+	Height  uint64
+
+	// Accounts contains the accounts with activity in the block, in the order they were
+	// requested.
+	Accounts []AccountStatus
+
+	receiveSpan
+}
+
+func (r AccountStatusesResponse) GetHeight() uint64 {
+	return r.Height
+}
+
+func (r AccountStatusesResponse) GetBlockID() flow.Identifier {
+	return r.BlockID
+}
+
+// IsHeartbeat returns true if the response contains no account activity.
+func (r AccountStatusesResponse) IsHeartbeat() bool {
+	return len(r.Accounts) == 0
+}
+
+// SubscribeAccountStatuses subscribes to the activity of the given accounts starting at the given
+// block ID or height. For each block with activity, it delivers the account creation, key,
+// contract and FlowToken deposit and withdrawal events for the accounts, and optionally the
+// registers modified in them. If a heartbeat interval is configured, blocks with no activity are
+// delivered at that interval.
+//
+// The version of the access API used by this client has no account statuses endpoint, so the
+// statuses are extracted from the execution data stream. Event payloads are decoded from
+// JSON-CDC, so this is not supported with verification enabled. Events whose payloads can't be
+// decoded are logged and skipped.
+func (c *ExecutionDataClient) SubscribeAccountStatuses(
+	ctx context.Context,
+	startBlockID flow.Identifier,
+	startHeight uint64,
+	addresses []flow.Address,
+	config AccountStatusConfig,
+	opts ...grpc.CallOption,
+) (*Subscription[AccountStatusesResponse], error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("at least one address is required")
+	}
+	if c.verifyExecutionData || c.verifyEvents {
+		return nil, fmt.Errorf("account statuses are not supported with verification enabled")
+	}
+
+	eventFields := make(map[string]string, len(accountEventFields)+2)
+	for eventType, field := range accountEventFields {
+		eventFields[eventType] = field
+	}
+
+	flowToken := config.FlowTokenAddress
+	if flowToken == "" && c.chain != nil {
+		flowToken = flowTokenAddresses[c.chain.ChainID()]
+	}
+	if flowToken != "" {
+		normalized, err := validateAddress(flowToken, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FlowToken address: %w", err)
+		}
+		eventFields["A."+normalized+".FlowToken.TokensDeposited"] = "to"
+		eventFields["A."+normalized+".FlowToken.TokensWithdrawn"] = "from"
+	} else {
+		c.log.Warn("FlowToken address unknown for chain, deposits and withdrawals are not included")
+	}
+
+	upstream, err := c.SubscribeLazyExecutionData(ctx, startBlockID, startHeight, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := newStreamSubscription[AccountStatusesResponse](streamAccountStatuses, c.metrics)
+	c.log.Debug("subscribed to account statuses",
+		F("subscription_id", sub.ID()),
+		F("accounts", len(addresses)),
+		F("start_block_id", startBlockID),
+		F("start_height", startHeight),
+	)
+
+	go func() {
+		defer close(sub.ch)
+
+		var heartbeat heartbeatTracker
+		if !c.heartbeat.SuppressHeartbeats {
+			heartbeat.interval = c.heartbeat.Interval
+		}
+
+		for response := range upstream.Channel() {
+			statuses, err := accountStatuses(response.ExecutionData, addresses, eventFields, config.IncludeRegisters,
+				func(event flow.Event, err error) {
+					c.log.Warn("skipping account event with undecodable payload",
+						F("subscription_id", sub.ID()),
+						F("height", response.Height),
+						F("block_id", response.BlockID),
+						F("event_type", event.Type),
+						F("transaction_id", event.TransactionID),
+						F("error", err),
+					)
+					c.metrics.Error("decode_event", err)
+				},
+			)
+			if err != nil {
+				c.log.Error("error extracting account statuses",
+					F("subscription_id", sub.ID()),
+					F("height", response.Height),
+					F("block_id", response.BlockID),
+					F("error", err),
+				)
+				sub.err = fmt.Errorf("error extracting account statuses at height %d: %w", response.Height, err)
+				return
+			}
+
+			if len(statuses) == 0 && !heartbeat.due(response.Height) {
+				continue
+			}
+
+			err = sub.send(ctx, AccountStatusesResponse{
+				BlockID:     response.BlockID,
+				Height:      response.Height,
+				Accounts:    statuses,
+				receiveSpan: response.receiveSpan,
+			})
+			if err != nil {
+				sub.err = err
+				return
+			}
+			c.metrics.DeliveredHeight(streamAccountStatuses, response.Height)
+			heartbeat.sent(response.Height)
+		}
+
+		sub.err = upstream.Err()
+	}()
+
+	return sub, nil
+}
+
+// accountStatuses returns the activity of the accounts in the block, in the order of addresses.
+// Events whose payload can't be decoded are passed to skip and left out.
+func accountStatuses(
+	execData *LazyExecutionData,
+	addresses []flow.Address,
+	eventFields map[string]string,
+	includeRegisters bool,
+	skip func(event flow.Event, err error),
+) ([]AccountStatus, error) {
+	statuses := make(map[flow.Address]*AccountStatus, len(addresses))
+	byAddress := make(map[string]flow.Address, len(addresses))
+	for _, address := range addresses {
+		byAddress[normalizeAddress(address.Hex())] = address
+	}
+
+	status := func(address flow.Address) *AccountStatus {
+		s, ok := statuses[address]
+		if !ok {
+			s = &AccountStatus{Address: address}
+			statuses[address] = s
+		}
+		return s
+	}
+
+	for i := 0; i < execData.NumChunks(); i++ {
+		events, err := execData.Events(i)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			field, ok := eventFields[string(event.Type)]
+			if !ok {
+				continue
+			}
+
+			fields, err := decodeEventFields(event.Payload)
+			if err != nil {
+				skip(event, err)
+				continue
+			}
+
+			value, ok := fields[field]
+			if !ok || value.kind != valueAddress {
+				continue
+			}
+			if address, ok := byAddress[value.str]; ok {
+				s := status(address)
+				s.Events = append(s.Events, event)
+			}
+		}
+
+		if !includeRegisters {
+			continue
+		}
+
+		trieUpdate, err := execData.TrieUpdate(i)
+		if err != nil {
+			return nil, fmt.Errorf("could not convert trie update for chunk %d: %w", i, err)
+		}
+		if trieUpdate == nil {
+			continue
+		}
+
+		for _, payload := range trieUpdate.Payloads {
+			id, ok := payloadRegisterID(payload)
+			if !ok || len(id.Owner) != flow.AddressLength {
+				continue
+			}
+			address := flow.BytesToAddress([]byte(id.Owner))
+			if _, ok := byAddress[normalizeAddress(address.Hex())]; !ok {
+				continue
+			}
+			s := status(address)
+			s.Registers = append(s.Registers, AccountRegister{ID: id, Value: payload.Value()})
+		}
+	}
+
+	var result []AccountStatus
+	for _, address := range addresses {
+		if s, ok := statuses[address]; ok {
+			result = append(result, *s)
+			// don't report the same account twice if it was requested twice
+			delete(statuses, address)
+		}
+	}
+
+	return result, nil
+}
+
+// payloadRegisterID returns the ID of the register updated by the payload.
+func payloadRegisterID(payload *ledger.Payload) (flow.RegisterID, bool) {
+	key, err := payload.Key()
+	if err != nil {
+		return flow.RegisterID{}, false
+	}
+
+	var id flow.RegisterID
+	for _, part := range key.KeyParts {
+		switch part.Type {
+		case ledger.KeyPartOwner:
+			id.Owner = string(part.Value)
+		case ledger.KeyPartKey:
+			id.Key = string(part.Value)
+		}
+	}
+
+	return id, true
+}
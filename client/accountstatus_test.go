@@ -0,0 +1,103 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLazyExecutionData returns execution data with a chunk for each list of events, with the
+// events already converted.
+func testLazyExecutionData(chunkEvents ...[]flow.Event) *LazyExecutionData {
+	d := &LazyExecutionData{}
+	for _, events := range chunkEvents {
+		chunk := &lazyChunk{}
+		events := events
+		chunk.eventsOnce.Do(func() {
+			chunk.events = events
+		})
+		d.chunks = append(d.chunks, chunk)
+	}
+	return d
+}
+
+// testAccountEvent returns an event with the address in the given payload field.
+func testAccountEvent(eventType string, field string, address string) flow.Event {
+	return flow.Event{
+		Type: flow.EventType(eventType),
+		Payload: []byte(fmt.Sprintf(
+			`{"type":"Event","value":{"id":%q,"fields":[{"name":%q,"value":{"type":"Address","value":%q}}]}}`,
+			eventType, field, address,
+		)),
+	}
+}
+
+func TestAccountStatuses(t *testing.T) {
+	alice := flow.HexToAddress("01")
+	bob := flow.HexToAddress("02")
+
+	eventFields := map[string]string{
+		"flow.AccountCreated":                          "address",
+		"A.1654653399040a61.FlowToken.TokensDeposited": "to",
+	}
+
+	created := testAccountEvent("flow.AccountCreated", "address", "0x0000000000000002")
+	deposited := testAccountEvent("A.1654653399040a61.FlowToken.TokensDeposited", "to", "0x01")
+	other := testAccountEvent("flow.AccountCreated", "address", "0x0000000000000003")
+	unrelated := testAccountEvent("A.1.Other.Event", "to", "0x01")
+	undecodable := flow.Event{Type: "flow.AccountCreated", Payload: []byte{0xd8, 0x82}}
+
+	t.Run("events grouped by account in requested order", func(t *testing.T) {
+		execData := testLazyExecutionData(
+			[]flow.Event{created, unrelated},
+			[]flow.Event{other, deposited},
+		)
+
+		statuses, err := accountStatuses(execData, []flow.Address{alice, bob, alice}, eventFields, false,
+			func(event flow.Event, err error) {
+				t.Fatalf("unexpected skipped event %s: %v", event.Type, err)
+			},
+		)
+		require.NoError(t, err)
+
+		require.Len(t, statuses, 2)
+		assert.Equal(t, alice, statuses[0].Address)
+		assert.Equal(t, []flow.Event{deposited}, statuses[0].Events)
+		assert.Equal(t, bob, statuses[1].Address)
+		assert.Equal(t, []flow.Event{created}, statuses[1].Events)
+	})
+
+	t.Run("undecodable payload skipped", func(t *testing.T) {
+		execData := testLazyExecutionData(
+			[]flow.Event{undecodable, created},
+			[]flow.Event{deposited},
+		)
+
+		var skipped []flow.Event
+		statuses, err := accountStatuses(execData, []flow.Address{alice, bob}, eventFields, false,
+			func(event flow.Event, err error) {
+				assert.Error(t, err)
+				skipped = append(skipped, event)
+			},
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, []flow.Event{undecodable}, skipped)
+		require.Len(t, statuses, 2)
+		assert.Equal(t, []flow.Event{deposited}, statuses[0].Events)
+		assert.Equal(t, []flow.Event{created}, statuses[1].Events)
+	})
+
+	t.Run("no activity", func(t *testing.T) {
+		execData := testLazyExecutionData([]flow.Event{other, unrelated})
+
+		statuses, err := accountStatuses(execData, []flow.Address{alice}, eventFields, false,
+			func(flow.Event, error) {},
+		)
+		require.NoError(t, err)
+		assert.Empty(t, statuses)
+	})
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAckMaxInFlight     = 1
+	defaultAckMaxRedeliveries = 3
+	defaultAckRedeliveryDelay = time.Second
+)
+
+type AckConfig[T BlockResponse] struct {
+	// MaxInFlight is the maximum number of delivered but unacknowledged responses. No new
+	// responses are delivered until an in-flight response is acknowledged. Defaults to 1, which
+	// delivers responses strictly in order.
+	MaxInFlight int
+
+	// MaxRedeliveries is the number of times a nacked response is redelivered before the
+	// subscription fails with the nack error. Defaults to 3.
+	MaxRedeliveries int
+
+	// RedeliveryDelay is the delay before a nacked response is redelivered. Defaults to 1s.
+	RedeliveryDelay time.Duration
+
+	// Commit is an optional callback called once a response and all responses delivered before
+	// it have been acknowledged. Responses are committed in delivery order. If Commit returns an
+	// error, the subscription fails.
+	Commit func(ctx context.Context, response T) error
+}
+
+// Delivery is a response delivered by an AckSubscription. Consumers must call either Ack or Nack
+// once they have finished processing it. Only the first call has any effect.
+type Delivery[T BlockResponse] struct {
+	Response T
+
+	// Attempt is the delivery attempt, starting at 1.
+	Attempt int
+
+	entry *ackEntry[T]
+	once  sync.Once
+	sub   *AckSubscription[T]
+}
+
+// Ack acknowledges that the response was processed successfully.
+func (d *Delivery[T]) Ack() {
+	d.resolve(nil)
+}
+
+// Nack signals that processing failed and the response should be redelivered.
+func (d *Delivery[T]) Nack(err error) {
+	if err == nil {
+		err = fmt.Errorf("nacked without error")
+	}
+	d.resolve(err)
+}
+
+func (d *Delivery[T]) resolve(err error) {
+	d.once.Do(func() {
+		select {
+		case d.sub.events <- ackEvent[T]{entry: d.entry, err: err}:
+		case <-d.sub.done:
+		}
+	})
+}
+
+type ackEntry[T BlockResponse] struct {
+	response T
+	attempts int
+	acked    bool
+}
+
+type ackEvent[T BlockResponse] struct {
+	entry *ackEntry[T]
+	err   error
+}
+
+// AckSubscription delivers responses from an underlying subscription with explicit
+// acknowledgement, providing at-least-once processing when combined with a Commit callback
+// that records progress, e.g. CheckpointSubscription.Commit.
+type AckSubscription[T BlockResponse] struct {
+	ch  chan *Delivery[T]
+	err error
+
+	config    AckConfig[T]
+	events    chan ackEvent[T]
+	redeliver chan *ackEntry[T]
+	done      chan struct{}
+}
+
+// WithAcks subscribes using subscribe, and wraps the subscription so that each response must be
+// acknowledged by the consumer. The context passed to subscribe is cancelled when the
+// AckSubscription stops, releasing the underlying subscription.
+func WithAcks[T BlockResponse](
+	ctx context.Context,
+	subscribe func(ctx context.Context) (*Subscription[T], error),
+	config AckConfig[T],
+) (*AckSubscription[T], error) {
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = defaultAckMaxInFlight
+	}
+	if config.MaxRedeliveries <= 0 {
+		config.MaxRedeliveries = defaultAckMaxRedeliveries
+	}
+	if config.RedeliveryDelay <= 0 {
+		config.RedeliveryDelay = defaultAckRedeliveryDelay
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	sub, err := subscribe(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s := &AckSubscription[T]{
+		ch:        make(chan *Delivery[T]),
+		config:    config,
+		events:    make(chan ackEvent[T]),
+		redeliver: make(chan *ackEntry[T]),
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		defer cancel()
+		s.run(ctx, sub)
+	}()
+
+	return s, nil
+}
+
+func (s *AckSubscription[T]) Channel() <-chan *Delivery[T] {
+	return s.ch
+}
+
+func (s *AckSubscription[T]) Err() error {
+	return s.err
+}
+
+func (s *AckSubscription[T]) run(ctx context.Context, sub *Subscription[T]) {
+	defer close(s.ch)
+	defer close(s.done)
+
+	// pending contains all delivered responses that have not been committed, in delivery order
+	var pending []*ackEntry[T]
+
+	// queue contains deliveries waiting to be sent to the consumer
+	var queue []*Delivery[T]
+
+	source := sub.Channel()
+	for {
+		if source == nil && len(pending) == 0 {
+			return
+		}
+
+		var in <-chan T
+		if source != nil && len(pending) < s.config.MaxInFlight {
+			in = source
+		}
+
+		var out chan *Delivery[T]
+		var next *Delivery[T]
+		if len(queue) > 0 {
+			out = s.ch
+			next = queue[0]
+		}
+
+		select {
+		case <-ctx.Done():
+			s.err = ctx.Err()
+			return
+
+		case response, ok := <-in:
+			if !ok {
+				if err := sub.Err(); err != nil {
+					s.err = err
+					return
+				}
+				source = nil
+				continue
+			}
+
+			entry := &ackEntry[T]{response: response}
+			pending = append(pending, entry)
+			queue = append(queue, s.newDelivery(entry))
+
+		case out <- next:
+			queue = queue[1:]
+
+		case event := <-s.events:
+			if event.err != nil {
+				if event.entry.attempts > s.config.MaxRedeliveries {
+					s.err = fmt.Errorf("response for height %d failed after %d attempts: %w",
+						event.entry.response.GetHeight(), event.entry.attempts, event.err)
+					return
+				}
+				s.scheduleRedelivery(event.entry)
+				continue
+			}
+
+			event.entry.acked = true
+			for len(pending) > 0 && pending[0].acked {
+				if s.config.Commit != nil {
+					if err := s.config.Commit(ctx, pending[0].response); err != nil {
+						s.err = fmt.Errorf("could not commit height %d: %w", pending[0].response.GetHeight(), err)
+						return
+					}
+				}
+				pending = pending[1:]
+			}
+
+		case entry := <-s.redeliver:
+			queue = append(queue, s.newDelivery(entry))
+		}
+	}
+}
+
+func (s *AckSubscription[T]) newDelivery(entry *ackEntry[T]) *Delivery[T] {
+	entry.attempts++
+	return &Delivery[T]{
+		Response: entry.response,
+		Attempt:  entry.attempts,
+		entry:    entry,
+		sub:      s,
+	}
+}
+
+func (s *AckSubscription[T]) scheduleRedelivery(entry *ackEntry[T]) {
+	time.AfterFunc(s.config.RedeliveryDelay, func() {
+		select {
+		case s.redeliver <- entry:
+		case <-s.done:
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSubscription returns a subscribe function delivering an events response for each height.
+func staticSubscription(heights ...uint64) func(context.Context) (*Subscription[EventsResponse], error) {
+	return func(ctx context.Context) (*Subscription[EventsResponse], error) {
+		sub := NewSubscription[EventsResponse]()
+		go func() {
+			defer close(sub.ch)
+			for _, height := range heights {
+				if err := sub.send(ctx, EventsResponse{Height: height}); err != nil {
+					sub.err = err
+					return
+				}
+			}
+		}()
+		return sub, nil
+	}
+}
+
+// receiveDelivery returns the next delivery, or nil if none is delivered within the timeout.
+func receiveDelivery[T BlockResponse](t *testing.T, s *AckSubscription[T], timeout time.Duration) *Delivery[T] {
+	t.Helper()
+
+	select {
+	case d, ok := <-s.Channel():
+		require.True(t, ok, "subscription closed: %v", s.Err())
+		return d
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func TestAckMaxInFlight(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxInFlight int
+		expected    int
+	}{
+		{name: "default", maxInFlight: 0, expected: 1},
+		{name: "one", maxInFlight: 1, expected: 1},
+		{name: "three", maxInFlight: 3, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			s, err := WithAcks(ctx, staticSubscription(1, 2, 3, 4, 5, 6), AckConfig[EventsResponse]{
+				MaxInFlight: tt.maxInFlight,
+			})
+			require.NoError(t, err)
+
+			var inFlight []*Delivery[EventsResponse]
+			for i := 0; i < tt.expected; i++ {
+				d := receiveDelivery(t, s, time.Second)
+				require.NotNil(t, d)
+				assert.Equal(t, uint64(i+1), d.Response.Height)
+				inFlight = append(inFlight, d)
+			}
+
+			// nothing else is delivered until a response is acknowledged
+			assert.Nil(t, receiveDelivery(t, s, 50*time.Millisecond))
+
+			inFlight[0].Ack()
+			d := receiveDelivery(t, s, time.Second)
+			require.NotNil(t, d)
+			assert.Equal(t, uint64(tt.expected+1), d.Response.Height)
+
+			assert.Nil(t, receiveDelivery(t, s, 50*time.Millisecond))
+		})
+	}
+}
+
+func TestAckCommitOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var committed []uint64
+
+	s, err := WithAcks(ctx, staticSubscription(1, 2, 3), AckConfig[EventsResponse]{
+		MaxInFlight: 3,
+		Commit: func(_ context.Context, response EventsResponse) error {
+			mu.Lock()
+			defer mu.Unlock()
+			committed = append(committed, response.Height)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	var deliveries []*Delivery[EventsResponse]
+	for i := 0; i < 3; i++ {
+		d := receiveDelivery(t, s, time.Second)
+		require.NotNil(t, d)
+		deliveries = append(deliveries, d)
+	}
+
+	// acknowledging out of order commits in delivery order once the earlier responses are acked
+	deliveries[2].Ack()
+	deliveries[1].Ack()
+	deliveries[0].Ack()
+
+	_, ok := <-s.Channel()
+	assert.False(t, ok)
+	assert.NoError(t, s.Err())
+	assert.Equal(t, []uint64{1, 2, 3}, committed)
+}
+
+func TestAckRedelivery(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, err := WithAcks(ctx, staticSubscription(1), AckConfig[EventsResponse]{
+		MaxRedeliveries: 1,
+		RedeliveryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	failure := errors.New("processing failed")
+
+	d := receiveDelivery(t, s, time.Second)
+	require.NotNil(t, d)
+	assert.Equal(t, 1, d.Attempt)
+	d.Nack(failure)
+
+	d = receiveDelivery(t, s, time.Second)
+	require.NotNil(t, d)
+	assert.Equal(t, 2, d.Attempt)
+	d.Nack(failure)
+
+	_, ok := <-s.Channel()
+	assert.False(t, ok)
+	assert.ErrorIs(t, s.Err(), failure)
+}
+
+func TestAckCancelsUnderlyingSubscription(t *testing.T) {
+	var subCtx context.Context
+	subscribe := func(ctx context.Context) (*Subscription[EventsResponse], error) {
+		subCtx = ctx
+		return staticSubscription(1, 2, 3)(ctx)
+	}
+
+	failure := errors.New("commit failed")
+	s, err := WithAcks(context.Background(), subscribe, AckConfig[EventsResponse]{
+		Commit: func(context.Context, EventsResponse) error {
+			return failure
+		},
+	})
+	require.NoError(t, err)
+
+	d := receiveDelivery(t, s, time.Second)
+	require.NotNil(t, d)
+	d.Ack()
+
+	for range s.Channel() {
+	}
+	assert.ErrorIs(t, s.Err(), failure)
+
+	// the subscription stopped with undelivered responses, so the underlying subscription must
+	// be cancelled for its goroutine to exit
+	select {
+	case <-subCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("underlying subscription was not cancelled")
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/credentials"
+)
+
+const defaultAPIKeyHeader = "x-api-key"
+
+// TokenProvider returns bearer tokens used to authenticate with the access node. Token is called
+// for every request and stream, so providers that fetch tokens remotely should cache them until
+// they expire.
+type TokenProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenProviderFunc adapts a function to a TokenProvider.
+type TokenProviderFunc func(ctx context.Context) (string, error)
+
+func (f TokenProviderFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticToken returns a TokenProvider that always returns the given token.
+func StaticToken(token string) TokenProvider {
+	return TokenProviderFunc(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// authCredentials adds API key and bearer token headers to every request.
+type authCredentials struct {
+	apiKeyHeader string
+	apiKey       string
+	tokens       TokenProvider
+	requireTLS   bool
+}
+
+var _ credentials.PerRPCCredentials = (*authCredentials)(nil)
+
+func (c *config) authCredentials() *authCredentials {
+	if c.apiKey == "" && c.tokenProvider == nil {
+		return nil
+	}
+
+	return &authCredentials{
+		apiKeyHeader: c.apiKeyHeader,
+		apiKey:       c.apiKey,
+		tokens:       c.tokenProvider,
+		requireTLS:   !c.insecureAuth,
+	}
+}
+
+func (a *authCredentials) headers(ctx context.Context) (map[string]string, error) {
+	headers := make(map[string]string, 2)
+	if a.apiKey != "" {
+		headers[a.apiKeyHeader] = a.apiKey
+	}
+	if a.tokens != nil {
+		token, err := a.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not get auth token: %w", err)
+		}
+		headers["authorization"] = "Bearer " + token
+	}
+	return headers, nil
+}
+
+func (a *authCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	return a.headers(ctx)
+}
+
+// RequireTransportSecurity causes grpc to fail requests on insecure connections, so credentials
+// are never sent in plaintext unless explicitly allowed.
+func (a *authCredentials) RequireTransportSecurity() bool {
+	return a.requireTLS
+}
+
+// httpHeader returns the headers for a websocket handshake.
+func (a *authCredentials) httpHeader(ctx context.Context, header http.Header) error {
+	headers, err := a.headers(ctx)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+	return nil
+}
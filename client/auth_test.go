@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/executiondata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// authServer is an in-process execution data API server recording the metadata of each request
+// and stream.
+type authServer struct {
+	listener *bufconn.Listener
+	requests chan metadata.MD
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	s := &authServer{
+		listener: bufconn.Listen(1 << 20),
+		requests: make(chan metadata.MD, 10),
+	}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(
+			func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+				md, _ := metadata.FromIncomingContext(ctx)
+				s.requests <- md
+				return handler(ctx, req)
+			},
+		),
+		grpc.StreamInterceptor(
+			func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+				md, _ := metadata.FromIncomingContext(stream.Context())
+				s.requests <- md
+				return handler(srv, stream)
+			},
+		),
+	)
+	executiondata.RegisterExecutionDataAPIServer(server, executiondata.UnimplementedExecutionDataAPIServer{})
+
+	go func() {
+		_ = server.Serve(s.listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return s
+}
+
+// dialOptions returns the options for an insecure connection to the server.
+func (s *authServer) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		headers map[string]string
+	}{
+		{
+			name:    "none",
+			headers: map[string]string{"x-api-key": "", "authorization": ""},
+		},
+		{
+			name:    "api key",
+			opts:    []Option{WithAPIKey("", "secret"), WithInsecureAuth()},
+			headers: map[string]string{"x-api-key": "secret", "authorization": ""},
+		},
+		{
+			name:    "api key custom header",
+			opts:    []Option{WithAPIKey("x-custom-key", "secret"), WithInsecureAuth()},
+			headers: map[string]string{"x-custom-key": "secret", "x-api-key": ""},
+		},
+		{
+			name:    "token",
+			opts:    []Option{WithTokenProvider(StaticToken("token")), WithInsecureAuth()},
+			headers: map[string]string{"authorization": "Bearer token", "x-api-key": ""},
+		},
+		{
+			name: "api key and token",
+			opts: []Option{
+				WithAPIKey("", "secret"), WithTokenProvider(StaticToken("token")), WithInsecureAuth(),
+			},
+			headers: map[string]string{"x-api-key": "secret", "authorization": "Bearer token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAuthServer(t)
+
+			opts := append([]Option{WithDialOptions(s.dialOptions()...)}, tt.opts...)
+			c, err := NewExecutionDataClientWithOptions("bufnet", flow.Emulator.Chain(), opts...)
+			require.NoError(t, err)
+			defer c.Close()
+
+			_, err = c.GetExecutionDataForBlockID(context.Background(), flow.Identifier{1})
+			require.Error(t, err, "the server doesn't implement the API")
+
+			md := <-s.requests
+			for key, expected := range tt.headers {
+				actual := strings.Join(md.Get(key), ",")
+				assert.Equal(t, expected, actual, "header %s", key)
+			}
+		})
+	}
+}
+
+func TestGRPCAuthRequiresTLS(t *testing.T) {
+	t.Run("insecure connection rejected at construction", func(t *testing.T) {
+		_, err := NewExecutionDataClientWithOptions("localhost:9000", flow.Emulator.Chain(), WithAPIKey("", "secret"))
+		assert.Error(t, err)
+	})
+
+	t.Run("insecure dial options rejected", func(t *testing.T) {
+		s := newAuthServer(t)
+
+		// grpc refuses to dial with credentials that require transport security over an insecure
+		// connection
+		_, err := NewExecutionDataClientWithOptions("bufnet", flow.Emulator.Chain(),
+			WithDialOptions(s.dialOptions()...),
+			WithAPIKey("", "secret"),
+		)
+		assert.Error(t, err)
+
+		select {
+		case md := <-s.requests:
+			t.Fatalf("request reached the server with metadata %v", md)
+		default:
+		}
+	})
+}
+
+func TestRestAuth(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	t.Run("headers sent with handshake", func(t *testing.T) {
+		c, err := NewRestClient(address,
+			WithAPIKey("", "secret"),
+			WithTokenProvider(StaticToken("token")),
+			WithInsecureAuth(),
+		)
+		require.NoError(t, err)
+
+		_, err = c.SubscribeEvents(context.Background(), flow.ZeroID, 1, EventFilter{})
+		require.Error(t, err, "the server rejects the handshake")
+
+		header := <-headers
+		assert.Equal(t, "secret", header.Get("x-api-key"))
+		assert.Equal(t, "Bearer token", header.Get("authorization"))
+		assert.Equal(t, defaultUserAgent, header.Get("User-Agent"))
+	})
+
+	t.Run("credentials require TLS", func(t *testing.T) {
+		c, err := NewRestClient(address, WithAPIKey("", "secret"))
+		require.NoError(t, err)
+
+		_, err = c.SubscribeEvents(context.Background(), flow.ZeroID, 1, EventFilter{})
+		require.Error(t, err)
+
+		select {
+		case header := <-headers:
+			t.Fatalf("handshake reached the server with headers %v", header)
+		default:
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultBackfillConcurrency = 10
+	defaultBackfillMaxAttempts = 5
+	defaultBackfillRetryDelay  = 500 * time.Millisecond
+)
+
+// BackfillCursor persists the next height to be processed by a backfill, allowing an interrupted
+// backfill to resume where it left off.
+//
+// A height is considered processed once the consumer receives the next height, so the last height
+// received before an interruption is delivered again when resuming. Once EndHeight is delivered,
+// the cursor is moved past it so a completed backfill is not repeated.
+type BackfillCursor interface {
+	// Load returns the next height to deliver. ok is false if no height has been stored yet.
+	Load(ctx context.Context) (height uint64, ok bool, err error)
+
+	// Store records that all heights before the given height have been processed.
+	Store(ctx context.Context, height uint64) error
+}
+
+// BackfillProgress is reported after each height is delivered.
+type BackfillProgress struct {
+	StartHeight uint64
+	EndHeight   uint64
+
+	// Height is the height that was just delivered.
+	Height uint64
+
+	// Delivered is the number of heights delivered so far, including heights delivered
+	// before a resume.
+	Delivered uint64
+}
+
+// Total returns the total number of heights in the backfill range.
+func (p BackfillProgress) Total() uint64 {
+	return p.EndHeight - p.StartHeight + 1
+}
+
+type BackfillConfig struct {
+	// StartHeight and EndHeight are the first and last (inclusive) heights to backfill.
+	StartHeight uint64
+	EndHeight   uint64
+
+	// Concurrency is the maximum number of in-flight requests. Defaults to 10.
+	Concurrency int
+
+	// MaxAttempts is the maximum number of attempts for each height before the backfill fails.
+	// Defaults to 5. Set to 1 to disable retries.
+	MaxAttempts int
+
+	// RetryDelay is the delay between attempts, doubled after each failure. Defaults to 500ms.
+	RetryDelay time.Duration
+
+	// Cursor is an optional cursor used to resume an interrupted backfill. If it has a stored
+	// height, the backfill starts there instead of StartHeight.
+	Cursor BackfillCursor
+
+	// OnProgress is an optional callback called after each height is delivered.
+	OnProgress func(BackfillProgress)
+}
+
+type backfillResult struct {
+	response ExecutionDataResponse
+	err      error
+}
+
+// Backfill fetches the execution data for a range of sealed heights concurrently, and delivers
+// the results in height order.
+//
+// Heights that fail with a transient error, including a NotAvailableError while the access node
+// indexes the block, are retried up to MaxAttempts. This is in addition to the client's retry
+// policy for individual requests (see WithRetry). A height that still fails ends the backfill.
+//
+// The subscription is closed after EndHeight is delivered, or when an error is encountered.
+func (c *ExecutionDataClient) Backfill(
+	ctx context.Context,
+	config BackfillConfig,
+	opts ...grpc.CallOption,
+) (*Subscription[ExecutionDataResponse], error) {
+	if config.EndHeight < config.StartHeight {
+		return nil, fmt.Errorf("end height %d is before start height %d", config.EndHeight, config.StartHeight)
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultBackfillConcurrency
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultBackfillMaxAttempts
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaultBackfillRetryDelay
+	}
+
+	startHeight := config.StartHeight
+	if config.Cursor != nil {
+		height, ok, err := config.Cursor.Load(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not load backfill cursor: %w", err)
+		}
+		if ok && height > startHeight {
+			startHeight = height
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	// pending holds one result slot per height in height order. Its capacity bounds the number
+	// of results fetched ahead of the consumer.
+	pending := make(chan chan backfillResult, config.Concurrency)
+
+	go func() {
+		defer close(pending)
+
+		sem := make(chan struct{}, config.Concurrency)
+		for height := startHeight; height <= config.EndHeight; height++ {
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
+
+			slot := make(chan backfillResult, 1)
+			go func(height uint64) {
+				defer func() { <-sem }()
+				slot <- c.backfillHeight(ctx, height, config, opts...)
+			}(height)
+
+			select {
+			case <-ctx.Done():
+				return
+			case pending <- slot:
+			}
+
+			if height == config.EndHeight {
+				// avoid overflow when EndHeight is the max uint64
+				return
+			}
+		}
+	}()
+
+	sub := NewSubscription[ExecutionDataResponse]()
+	go func() {
+		defer close(sub.ch)
+		defer cancel()
+
+		delivered := startHeight - config.StartHeight
+		for slot := range pending {
+			var result backfillResult
+			select {
+			case <-ctx.Done():
+				sub.err = ctx.Err()
+				return
+			case result = <-slot:
+			}
+
+			if result.err != nil {
+				sub.err = result.err
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				sub.err = ctx.Err()
+				return
+			case sub.ch <- result.response:
+			}
+			delivered++
+
+			// the consumer has received this height, so it has finished processing the previous
+			if config.Cursor != nil && result.response.Height > startHeight {
+				if err := config.Cursor.Store(ctx, result.response.Height); err != nil {
+					sub.err = fmt.Errorf("could not store backfill cursor: %w", err)
+					return
+				}
+			}
+
+			if config.OnProgress != nil {
+				config.OnProgress(BackfillProgress{
+					StartHeight: config.StartHeight,
+					EndHeight:   config.EndHeight,
+					Height:      result.response.Height,
+					Delivered:   delivered,
+				})
+			}
+		}
+
+		if ctx.Err() != nil {
+			sub.err = ctx.Err()
+			return
+		}
+
+		// every height was delivered, so move the cursor past the end of the range. This is
+		// skipped when EndHeight is the max uint64, since there's no height after it.
+		if config.Cursor != nil && config.EndHeight < math.MaxUint64 {
+			if err := config.Cursor.Store(ctx, config.EndHeight+1); err != nil {
+				sub.err = fmt.Errorf("could not store backfill cursor: %w", err)
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// backfillHeight fetches the execution data for a single height, retrying transient failures.
+func (c *ExecutionDataClient) backfillHeight(
+	ctx context.Context,
+	height uint64,
+	config BackfillConfig,
+	opts ...grpc.CallOption,
+) backfillResult {
+	delay := config.RetryDelay
+
+	for attempt := 1; ; attempt++ {
+		execData, err := c.executionData().GetExecutionDataByHeight(ctx, height, opts...)
+		if err == nil {
+			return backfillResult{
+				response: ExecutionDataResponse{
+					BlockID:       execData.BlockID,
+					Height:        height,
+					ExecutionData: execData,
+				},
+			}
+		}
+
+		if attempt >= config.MaxAttempts || !isTransient(err) {
+			return backfillResult{
+				err: fmt.Errorf("could not get execution data for height %d after %d attempts: %w", height, attempt, err),
+			}
+		}
+
+		c.log.Debug("retrying backfill height",
+			F("height", height),
+			F("attempt", attempt),
+			F("delay", delay),
+			F("error", err),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return backfillResult{err: ctx.Err()}
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
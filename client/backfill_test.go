@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// memoryCursor is a BackfillCursor recording every stored height.
+type memoryCursor struct {
+	mu     sync.Mutex
+	stored []uint64
+}
+
+func (c *memoryCursor) Load(context.Context) (uint64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.stored) == 0 {
+		return 0, false, nil
+	}
+	return c.stored[len(c.stored)-1], true, nil
+}
+
+func (c *memoryCursor) Store(_ context.Context, height uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stored = append(c.stored, height)
+	return nil
+}
+
+// backfillHeights returns the heights delivered by the backfill, and its error.
+func backfillHeights(t *testing.T, c *ExecutionDataClient, config BackfillConfig) ([]uint64, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sub, err := c.Backfill(ctx, config)
+	require.NoError(t, err)
+
+	var heights []uint64
+	for response := range sub.Channel() {
+		assert.Equal(t, testBlockID(response.Height, 0), response.BlockID)
+		assert.Equal(t, response.BlockID, response.ExecutionData.BlockID)
+		heights = append(heights, response.Height)
+	}
+	return heights, sub.Err()
+}
+
+func TestBackfill(t *testing.T) {
+	t.Run("heights delivered in order to the end of the range", func(t *testing.T) {
+		c := newTestClient(newTestAccessNode(100))
+
+		var progress []BackfillProgress
+		heights, err := backfillHeights(t, c, BackfillConfig{
+			StartHeight: 10,
+			EndHeight:   30,
+			Concurrency: 4,
+			OnProgress: func(p BackfillProgress) {
+				progress = append(progress, p)
+			},
+		})
+		require.NoError(t, err)
+
+		require.Len(t, heights, 21)
+		for i, height := range heights {
+			assert.Equal(t, uint64(10+i), height)
+		}
+
+		require.Len(t, progress, 21)
+		last := progress[len(progress)-1]
+		assert.Equal(t, uint64(30), last.Height)
+		assert.Equal(t, last.Total(), last.Delivered)
+	})
+
+	t.Run("cursor resumes and moves past the end", func(t *testing.T) {
+		c := newTestClient(newTestAccessNode(100))
+		cursor := &memoryCursor{stored: []uint64{15}}
+		config := BackfillConfig{StartHeight: 10, EndHeight: 20, Cursor: cursor}
+
+		heights, err := backfillHeights(t, c, config)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{15, 16, 17, 18, 19, 20}, heights)
+
+		// each height is stored once the next is received, then the end of the range is passed
+		assert.Equal(t, []uint64{15, 16, 17, 18, 19, 20, 21}, cursor.stored)
+
+		// a completed backfill delivers nothing when run again
+		heights, err = backfillHeights(t, c, config)
+		require.NoError(t, err)
+		assert.Empty(t, heights)
+	})
+
+	t.Run("cursor not moved past the end when interrupted", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		node.fail("GetExecutionDataByBlockID", status.Error(codes.PermissionDenied, "denied"))
+		c := newTestClient(node)
+		cursor := &memoryCursor{}
+
+		_, err := backfillHeights(t, c, BackfillConfig{StartHeight: 10, EndHeight: 20, Concurrency: 1, Cursor: cursor})
+		require.Error(t, err)
+
+		// the first height failed, so nothing was processed
+		assert.Empty(t, cursor.stored)
+	})
+
+	t.Run("transient failures retried by default", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		node.fail("GetExecutionDataByBlockID",
+			status.Error(codes.NotFound, "not indexed"),
+			status.Error(codes.Unavailable, "unavailable"),
+		)
+		c := newTestClient(node)
+
+		heights, err := backfillHeights(t, c, BackfillConfig{
+			StartHeight: 10,
+			EndHeight:   12,
+			Concurrency: 1,
+			RetryDelay:  time.Millisecond,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{10, 11, 12}, heights)
+		assert.Equal(t, 5, node.callCount("GetExecutionDataByBlockID"))
+	})
+
+	t.Run("fails after max attempts", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		node.fail("GetExecutionDataByBlockID",
+			status.Error(codes.Unavailable, "unavailable"),
+			status.Error(codes.Unavailable, "unavailable"),
+		)
+		c := newTestClient(node)
+
+		heights, err := backfillHeights(t, c, BackfillConfig{
+			StartHeight: 10,
+			EndHeight:   12,
+			Concurrency: 1,
+			MaxAttempts: 2,
+			RetryDelay:  time.Millisecond,
+		})
+		require.Error(t, err)
+		assert.ErrorContains(t, err, "after 2 attempts")
+		assert.Empty(t, heights)
+	})
+
+	t.Run("permanent failures not retried", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		node.fail("GetExecutionDataByBlockID", status.Error(codes.PermissionDenied, "denied"))
+		c := newTestClient(node)
+
+		_, err := backfillHeights(t, c, BackfillConfig{StartHeight: 10, EndHeight: 10, RetryDelay: time.Millisecond})
+		require.Error(t, err)
+		assert.Equal(t, 1, node.callCount("GetExecutionDataByBlockID"))
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		c := newTestClient(newTestAccessNode(100))
+
+		_, err := c.Backfill(context.Background(), BackfillConfig{StartHeight: 20, EndHeight: 10})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/onflow/flow-go/model/flow"
+)
+
+const (
+	defaultBrokerBufferSize = 100
+
+	streamBrokerEvents = "broker_events"
+)
+
+type BrokerConfig struct {
+	// BufferSize is the number of responses buffered for each subscriber. A subscriber whose
+	// buffer fills up is closed with an *ErrSlowConsumer, so it can't hold up the others.
+	// Defaults to 100.
+	BufferSize int
+
+	// HeartbeatInterval is the number of blocks after which a subscriber receives a response,
+	// even if there were no matching events. 0 disables heartbeats.
+	HeartbeatInterval uint64
+}
+
+// EventBroker shares a single upstream execution data subscription between many local event
+// subscribers, each with their own filter. Subscribers can be added and removed at any time,
+// and receive events from the next block processed by the broker.
+type EventBroker struct {
+	client *ExecutionDataClient
+	config BrokerConfig
+	log    Logger
+
+	mu          sync.Mutex
+	subscribers map[uint64]*brokerSubscriber
+	closed      bool
+	err         error
+}
+
+type brokerSubscriber struct {
+	sub       *Subscription[EventsResponse]
+	filter    EventFilter
+	heartbeat heartbeatTracker
+}
+
+// NewEventBroker starts a broker subscribed to execution data from the given start height, or
+// the latest block if 0. The broker stops when the context is done, or the upstream subscription
+// fails.
+func NewEventBroker(
+	ctx context.Context,
+	client *ExecutionDataClient,
+	startHeight uint64,
+	config BrokerConfig,
+) (*EventBroker, error) {
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultBrokerBufferSize
+	}
+
+	// only events are needed, so the lazy view avoids converting the rest of each block
+	upstream, err := client.SubscribeLazyExecutionData(ctx, flow.ZeroID, startHeight)
+	if err != nil {
+		return nil, fmt.Errorf("could not subscribe to execution data: %w", err)
+	}
+
+	b := &EventBroker{
+		client:      client,
+		config:      config,
+		log:         client.log,
+		subscribers: make(map[uint64]*brokerSubscriber),
+	}
+
+	go b.run(upstream)
+
+	return b, nil
+}
+
+// Subscribe adds a subscriber receiving the events selected by the filter. An error is returned
+// if the filter fails validation for the client's chain.
+func (b *EventBroker) Subscribe(filter EventFilter) (*Subscription[EventsResponse], error) {
+	if err := filter.Validate(b.client.chain); err != nil {
+		return nil, fmt.Errorf("invalid event filter: %w", err)
+	}
+
+	sub := &Subscription[EventsResponse]{
+		id:      nextSubscriptionID.Add(1),
+		ch:      make(chan EventsResponse, b.config.BufferSize),
+		stream:  streamBrokerEvents,
+		metrics: b.client.metrics,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		sub.err = b.err
+		close(sub.ch)
+		return sub, nil
+	}
+
+	b.subscribers[sub.ID()] = &brokerSubscriber{
+		sub:       sub,
+		filter:    filter,
+		heartbeat: heartbeatTracker{interval: b.config.HeartbeatInterval},
+	}
+	b.log.Debug("added broker subscriber", F("subscription_id", sub.ID()))
+
+	return sub, nil
+}
+
+// Unsubscribe removes the subscriber and closes its channel. Buffered responses are discarded.
+func (b *EventBroker) Unsubscribe(sub *Subscription[EventsResponse]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub.ID()]; !ok {
+		return
+	}
+
+	delete(b.subscribers, sub.ID())
+	close(sub.ch)
+	b.log.Debug("removed broker subscriber", F("subscription_id", sub.ID()))
+}
+
+// Err returns the error that stopped the broker, if any.
+func (b *EventBroker) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.err
+}
+
+func (b *EventBroker) run(upstream *Subscription[LazyExecutionDataResponse]) {
+	for response := range upstream.Channel() {
+		b.publish(response.Height, response.BlockID, response.ExecutionData.AllEvents())
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.err = upstream.Err()
+	for id, subscriber := range b.subscribers {
+		subscriber.sub.err = b.err
+		close(subscriber.sub.ch)
+		delete(b.subscribers, id)
+	}
+
+	b.log.Debug("event broker stopped", F("error", b.err))
+}
+
+// publish sends the block's matching events to each subscriber without blocking.
+func (b *EventBroker) publish(height uint64, blockID flow.Identifier, events flow.EventsList) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, subscriber := range b.subscribers {
+		var matching []flow.Event
+		for _, event := range events {
+			if subscriber.filter.matches(event.Type) {
+				matching = append(matching, event)
+			}
+		}
+
+		if len(matching) == 0 && !subscriber.heartbeat.due(height) {
+			continue
+		}
+
+		select {
+		case subscriber.sub.ch <- EventsResponse{Height: height, BlockID: blockID, Events: matching}:
+			subscriber.heartbeat.sent(height)
+		default:
+			b.log.Warn("closing slow broker subscriber",
+				F("subscription_id", id),
+				F("height", height),
+			)
+			subscriber.sub.err = &ErrSlowConsumer{Height: height, BufferSize: b.config.BufferSize}
+			close(subscriber.sub.ch)
+			delete(b.subscribers, id)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBrokerSubscribe(t *testing.T) {
+	newBroker := func() *EventBroker {
+		return &EventBroker{
+			client:      &ExecutionDataClient{metrics: NoopMetrics{}},
+			config:      BrokerConfig{BufferSize: 1},
+			log:         NoopLogger{},
+			subscribers: make(map[uint64]*brokerSubscriber),
+		}
+	}
+
+	t.Run("invalid filter", func(t *testing.T) {
+		b := newBroker()
+
+		sub, err := b.Subscribe(EventFilter{EventTypes: []string{"FlowToken.TokensDeposited"}})
+		assert.Error(t, err)
+		assert.Nil(t, sub)
+		assert.Empty(t, b.subscribers)
+	})
+
+	t.Run("filter normalized", func(t *testing.T) {
+		b := newBroker()
+
+		sub, err := b.Subscribe(EventFilter{Contracts: []string{" A.0x1654653399040a61.FlowToken", "A.1654653399040a61.FlowToken"}})
+		require.NoError(t, err)
+		require.Contains(t, b.subscribers, sub.ID())
+		assert.Len(t, b.subscribers[sub.ID()].filter.Contracts, 1)
+
+		b.Unsubscribe(sub)
+		_, ok := <-sub.Channel()
+		assert.False(t, ok)
+	})
+
+	t.Run("closed broker", func(t *testing.T) {
+		b := newBroker()
+		b.closed = true
+		b.err = errors.New("upstream failed")
+
+		sub, err := b.Subscribe(EventFilter{})
+		require.NoError(t, err)
+		_, ok := <-sub.Channel()
+		assert.False(t, ok)
+		assert.ErrorIs(t, sub.Err(), b.err)
+	})
+}
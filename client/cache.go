@@ -0,0 +1,460 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultCacheMaxMemoryBytes = 256 << 20
+	defaultCacheDiskMaxBytes   = 4 << 30
+	defaultCacheDiskTTL        = 24 * time.Hour
+
+	// diskCachePruneInterval is the interval at which expired files are removed from the disk
+	// cache when new files are added
+	diskCachePruneInterval = 10 * time.Minute
+
+	cacheFileExt = ".pb.gz"
+
+	// cache tiers used to label metrics
+	cacheTierMemory = "memory"
+	cacheTierDisk   = "disk"
+)
+
+type CacheConfig struct {
+	// MaxMemoryBytes is the maximum encoded size of the execution data kept in memory.
+	// Defaults to 256MiB.
+	MaxMemoryBytes int64
+
+	// DiskDir is the directory used for the on-disk cache. The on-disk cache is disabled if empty.
+	DiskDir string
+
+	// DiskMaxBytes is the maximum total size of the files in the on-disk cache. The oldest files
+	// are removed first when the limit is exceeded. Defaults to 4GiB.
+	DiskMaxBytes int64
+
+	// DiskTTL is the maximum age of files in the on-disk cache. Defaults to 24h.
+	DiskTTL time.Duration
+}
+
+// CachingClient is an ExecutionDataClient that caches execution data lookups by block ID.
+//
+// Execution data is kept in an in-memory LRU, and optionally in compressed files on disk.
+// Concurrent lookups for the same block are deduplicated into a single request. Lookups made
+// by the client's other methods, such as Backfill, gap repairs and event verification, also
+// use the cache.
+//
+// Cached responses are shared between callers and must not be modified.
+type CachingClient struct {
+	*ExecutionDataClient
+
+	memory *memoryCache
+	disk   *diskCache
+	group  singleflight.Group
+}
+
+// NewCachingClient returns a CachingClient that caches lookups made using the given client.
+func NewCachingClient(client *ExecutionDataClient, config CacheConfig) (*CachingClient, error) {
+	if config.MaxMemoryBytes == 0 {
+		config.MaxMemoryBytes = defaultCacheMaxMemoryBytes
+	}
+	if config.DiskMaxBytes == 0 {
+		config.DiskMaxBytes = defaultCacheDiskMaxBytes
+	}
+	if config.DiskTTL == 0 {
+		config.DiskTTL = defaultCacheDiskTTL
+	}
+
+	// the wrapped client is copied so its internal lookups can use the cache without
+	// affecting other users of the client
+	cached := *client
+
+	c := &CachingClient{
+		ExecutionDataClient: &cached,
+		memory:              newMemoryCache(config.MaxMemoryBytes),
+	}
+	cached.lookup = c
+
+	if config.DiskDir != "" {
+		disk, err := newDiskCache(config.DiskDir, config.DiskMaxBytes, config.DiskTTL)
+		if err != nil {
+			return nil, err
+		}
+		c.disk = disk
+	}
+
+	return c, nil
+}
+
+// GetExecutionDataForBlockID returns the BlockExecutionData for the given block ID, using the
+// cache when possible.
+func (c *CachingClient) GetExecutionDataForBlockID(
+	ctx context.Context,
+	blockID flow.Identifier,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	if execData, ok := c.memory.get(blockID); ok {
+		c.metrics.CacheLookup(cacheTierMemory, true)
+		return execData, nil
+	}
+	c.metrics.CacheLookup(cacheTierMemory, false)
+
+	// the shared lookup is not tied to any single caller so one caller cancelling does not fail
+	// the others waiting on the same block.
+	ch := c.group.DoChan(blockID.String(), func() (interface{}, error) {
+		return c.load(detachedContext{ctx}, blockID, opts...)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*execution_data.BlockExecutionData), nil
+	}
+}
+
+// GetExecutionDataByHeight returns the BlockExecutionData for the sealed block at the given
+// height, using the cache when possible.
+func (c *CachingClient) GetExecutionDataByHeight(
+	ctx context.Context,
+	height uint64,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	blockID, err := c.GetSealedBlockID(ctx, height, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	execData, err := c.GetExecutionDataForBlockID(ctx, blockID, opts...)
+	if err != nil {
+		var notAvailable *NotAvailableError
+		if errors.As(err, &notAvailable) {
+			notAvailable.Height = height
+		}
+		return nil, err
+	}
+
+	return execData, nil
+}
+
+// load returns the execution data for the block from disk, or from the access node, and adds
+// it to the cache.
+func (c *CachingClient) load(
+	ctx context.Context,
+	blockID flow.Identifier,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	// another lookup may have populated the cache while this one was waiting
+	if execData, ok := c.memory.get(blockID); ok {
+		return execData, nil
+	}
+
+	var m *entities.BlockExecutionData
+	if c.disk != nil {
+		var err error
+		m, err = c.disk.get(blockID)
+		if err != nil {
+			c.log.Warn("could not read execution data from disk cache",
+				F("block_id", blockID), F("error", err))
+		}
+		c.metrics.CacheLookup(cacheTierDisk, m != nil)
+	}
+
+	fromDisk := m != nil
+	if !fromDisk {
+		var err error
+		m, err = c.getExecutionDataMessage(ctx, blockID, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	execData, err := c.executionDataFromMessage(ctx, m, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.memory.add(blockID, execData, int64(proto.Size(m)))
+
+	if c.disk != nil && !fromDisk {
+		if err := c.disk.add(blockID, m); err != nil {
+			c.log.Warn("could not write execution data to disk cache",
+				F("block_id", blockID), F("error", err))
+		}
+	}
+
+	return execData, nil
+}
+
+type memoryCacheEntry struct {
+	blockID  flow.Identifier
+	execData *execution_data.BlockExecutionData
+	size     int64
+}
+
+// memoryCache is an LRU cache of execution data bounded by the total encoded size of its entries.
+type memoryCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	order    *list.List
+	entries  map[flow.Identifier]*list.Element
+}
+
+func newMemoryCache(maxBytes int64) *memoryCache {
+	return &memoryCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[flow.Identifier]*list.Element),
+	}
+}
+
+func (m *memoryCache) get(blockID flow.Identifier) (*execution_data.BlockExecutionData, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[blockID]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+
+	return elem.Value.(*memoryCacheEntry).execData, true
+}
+
+func (m *memoryCache) add(blockID flow.Identifier, execData *execution_data.BlockExecutionData, size int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// entries larger than the whole cache would only evict everything else
+	if size > m.maxBytes {
+		return
+	}
+
+	if elem, ok := m.entries[blockID]; ok {
+		m.order.MoveToFront(elem)
+		return
+	}
+
+	m.entries[blockID] = m.order.PushFront(&memoryCacheEntry{
+		blockID:  blockID,
+		execData: execData,
+		size:     size,
+	})
+	m.size += size
+
+	for m.size > m.maxBytes {
+		oldest := m.order.Back()
+		entry := oldest.Value.(*memoryCacheEntry)
+		m.order.Remove(oldest)
+		delete(m.entries, entry.blockID)
+		m.size -= entry.size
+	}
+}
+
+// diskCache stores gzip compressed execution data messages in files named by block ID.
+type diskCache struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	ttl      time.Duration
+	size     int64
+
+	// pruned is the time expired files were last removed
+	pruned time.Time
+}
+
+func newDiskCache(dir string, maxBytes int64, ttl time.Duration) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %w", err)
+	}
+
+	d := &diskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		ttl:      ttl,
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.evict(); err != nil {
+		return nil, fmt.Errorf("could not prune cache directory: %w", err)
+	}
+
+	return d, nil
+}
+
+func (d *diskCache) path(blockID flow.Identifier) string {
+	return filepath.Join(d.dir, blockID.String()+cacheFileExt)
+}
+
+// get returns the cached message for the block, or nil if it is not cached or has expired.
+func (d *diskCache) get(blockID flow.Identifier) (*entities.BlockExecutionData, error) {
+	path := d.path(blockID)
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(info.ModTime()) > d.ttl {
+		return nil, d.removeExpired(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var m entities.BlockExecutionData
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (d *diskCache) add(blockID flow.Identifier, m *entities.BlockExecutionData) error {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := writeFileAtomic(d.path(blockID), buf.Bytes()); err != nil {
+		return err
+	}
+
+	d.size += int64(buf.Len())
+	if d.size > d.maxBytes || time.Since(d.pruned) > diskCachePruneInterval {
+		return d.evict()
+	}
+
+	return nil
+}
+
+// removeExpired removes the file if it has expired. It's checked again while holding the lock,
+// since the file may have been replaced.
+func (d *diskCache) removeExpired(path string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if time.Since(info.ModTime()) <= d.ttl {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	d.size -= info.Size()
+
+	return nil
+}
+
+// evict removes expired files, then the oldest files until the cache is within its size limit.
+// evict must be called while holding the lock.
+func (d *diskCache) evict() error {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), cacheFileExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// the file was removed since the directory was read
+			continue
+		}
+		files = append(files, info)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	var size int64
+	for _, info := range files {
+		size += info.Size()
+	}
+
+	for _, info := range files {
+		if size <= d.maxBytes && time.Since(info.ModTime()) <= d.ttl {
+			break
+		}
+		if err := os.Remove(filepath.Join(d.dir, info.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		size -= info.Size()
+	}
+
+	d.size = size
+	d.pruned = time.Now()
+
+	return nil
+}
+
+// detachedContext is a context that keeps the values of its parent, but is never cancelled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
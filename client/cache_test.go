@@ -0,0 +1,220 @@
+package client
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCacheEviction(t *testing.T) {
+	type op struct {
+		// add adds the block with the given size, otherwise the block is looked up
+		add  bool
+		id   byte
+		size int64
+	}
+
+	tests := []struct {
+		name     string
+		maxBytes int64
+		ops      []op
+		cached   []byte
+		evicted  []byte
+	}{
+		{
+			name:     "within limit",
+			maxBytes: 30,
+			ops:      []op{{add: true, id: 1, size: 10}, {add: true, id: 2, size: 10}, {add: true, id: 3, size: 10}},
+			cached:   []byte{1, 2, 3},
+		},
+		{
+			name:     "least recently added evicted",
+			maxBytes: 30,
+			ops: []op{
+				{add: true, id: 1, size: 10}, {add: true, id: 2, size: 10}, {add: true, id: 3, size: 10},
+				{add: true, id: 4, size: 10},
+			},
+			cached:  []byte{2, 3, 4},
+			evicted: []byte{1},
+		},
+		{
+			name:     "lookup refreshes entry",
+			maxBytes: 30,
+			ops: []op{
+				{add: true, id: 1, size: 10}, {add: true, id: 2, size: 10}, {add: true, id: 3, size: 10},
+				{id: 1},
+				{add: true, id: 4, size: 10},
+			},
+			cached:  []byte{1, 3, 4},
+			evicted: []byte{2},
+		},
+		{
+			name:     "re-adding refreshes entry",
+			maxBytes: 30,
+			ops: []op{
+				{add: true, id: 1, size: 10}, {add: true, id: 2, size: 10}, {add: true, id: 3, size: 10},
+				{add: true, id: 1, size: 10},
+				{add: true, id: 4, size: 10},
+			},
+			cached:  []byte{1, 3, 4},
+			evicted: []byte{2},
+		},
+		{
+			name:     "large entry evicts several",
+			maxBytes: 30,
+			ops: []op{
+				{add: true, id: 1, size: 10}, {add: true, id: 2, size: 10}, {add: true, id: 3, size: 10},
+				{add: true, id: 4, size: 25},
+			},
+			cached:  []byte{4},
+			evicted: []byte{1, 2, 3},
+		},
+		{
+			name:     "entry larger than cache not added",
+			maxBytes: 30,
+			ops:      []op{{add: true, id: 1, size: 10}, {add: true, id: 2, size: 31}},
+			cached:   []byte{1},
+			evicted:  []byte{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMemoryCache(tt.maxBytes)
+
+			for _, op := range tt.ops {
+				blockID := flow.Identifier{op.id}
+				if op.add {
+					m.add(blockID, &execution_data.BlockExecutionData{BlockID: blockID}, op.size)
+				} else {
+					_, ok := m.get(blockID)
+					require.True(t, ok)
+				}
+			}
+
+			for _, id := range tt.cached {
+				execData, ok := m.get(flow.Identifier{id})
+				if assert.True(t, ok, "block %d should be cached", id) {
+					assert.Equal(t, flow.Identifier{id}, execData.BlockID)
+				}
+			}
+			for _, id := range tt.evicted {
+				_, ok := m.get(flow.Identifier{id})
+				assert.False(t, ok, "block %d should not be cached", id)
+			}
+			assert.LessOrEqual(t, m.size, tt.maxBytes)
+		})
+	}
+}
+
+// testExecutionDataMessage returns an execution data message for the block.
+func testExecutionDataMessage(blockID flow.Identifier) *entities.BlockExecutionData {
+	return &entities.BlockExecutionData{BlockId: blockID[:]}
+}
+
+// setAge sets the modification time of the block's file in the disk cache.
+func setAge(t *testing.T, d *diskCache, blockID flow.Identifier, age time.Duration) {
+	t.Helper()
+	modTime := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(d.path(blockID), modTime, modTime))
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	blockIDs := []flow.Identifier{{1}, {2}, {3}, {4}}
+
+	t.Run("oldest evicted over size limit", func(t *testing.T) {
+		d, err := newDiskCache(t.TempDir(), 1<<30, time.Hour)
+		require.NoError(t, err)
+
+		for i, blockID := range blockIDs[:3] {
+			require.NoError(t, d.add(blockID, testExecutionDataMessage(blockID)))
+			setAge(t, d, blockID, time.Duration(10-i)*time.Minute)
+		}
+
+		// leave room for two files, so adding a fourth evicts the two oldest
+		fileSize := d.size / 3
+		d.maxBytes = 2*fileSize + fileSize/2
+		require.NoError(t, d.add(blockIDs[3], testExecutionDataMessage(blockIDs[3])))
+
+		for i, blockID := range blockIDs {
+			m, err := d.get(blockID)
+			require.NoError(t, err)
+			if i < 2 {
+				assert.Nil(t, m, "block %d should be evicted", i)
+			} else {
+				assert.NotNil(t, m, "block %d should be cached", i)
+			}
+		}
+		assert.LessOrEqual(t, d.size, d.maxBytes)
+	})
+
+	t.Run("expired file removed on lookup", func(t *testing.T) {
+		d, err := newDiskCache(t.TempDir(), 1<<30, time.Hour)
+		require.NoError(t, err)
+
+		require.NoError(t, d.add(blockIDs[0], testExecutionDataMessage(blockIDs[0])))
+		setAge(t, d, blockIDs[0], 2*time.Hour)
+
+		m, err := d.get(blockIDs[0])
+		require.NoError(t, err)
+		assert.Nil(t, m)
+
+		_, err = os.Stat(d.path(blockIDs[0]))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.Equal(t, int64(0), d.size)
+	})
+
+	t.Run("expired files pruned when adding", func(t *testing.T) {
+		d, err := newDiskCache(t.TempDir(), 1<<30, time.Hour)
+		require.NoError(t, err)
+
+		require.NoError(t, d.add(blockIDs[0], testExecutionDataMessage(blockIDs[0])))
+		setAge(t, d, blockIDs[0], 2*time.Hour)
+
+		// the next add prunes once the prune interval has passed
+		d.pruned = time.Now().Add(-2 * diskCachePruneInterval)
+		require.NoError(t, d.add(blockIDs[1], testExecutionDataMessage(blockIDs[1])))
+
+		_, err = os.Stat(d.path(blockIDs[0]))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(d.path(blockIDs[1]))
+		assert.NoError(t, err)
+	})
+
+	t.Run("expired files pruned on startup", func(t *testing.T) {
+		dir := t.TempDir()
+		d, err := newDiskCache(dir, 1<<30, time.Hour)
+		require.NoError(t, err)
+
+		require.NoError(t, d.add(blockIDs[0], testExecutionDataMessage(blockIDs[0])))
+		require.NoError(t, d.add(blockIDs[1], testExecutionDataMessage(blockIDs[1])))
+		setAge(t, d, blockIDs[0], 2*time.Hour)
+
+		d, err = newDiskCache(dir, 1<<30, time.Hour)
+		require.NoError(t, err)
+
+		_, err = os.Stat(d.path(blockIDs[0]))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		m, err := d.get(blockIDs[1])
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		assert.Equal(t, blockIDs[1][:], m.GetBlockId())
+	})
+}
+
+func TestCachingClientInternalLookups(t *testing.T) {
+	client := &ExecutionDataClient{}
+	c, err := NewCachingClient(client, CacheConfig{})
+	require.NoError(t, err)
+
+	// lookups made by the client's other methods use the cache, without changing the wrapped client
+	assert.Same(t, c, c.executionData())
+	assert.Same(t, client, client.executionData())
+}
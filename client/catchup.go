@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/onflow/flow-go/model/flow"
+	"google.golang.org/grpc"
+)
+
+const defaultLiveThreshold = 100
+
+type CatchUpPhase int32
+
+const (
+	// CatchUpPhaseBackfill indicates historical heights are being fetched using request/response calls.
+	CatchUpPhaseBackfill CatchUpPhase = iota
+
+	// CatchUpPhaseLive indicates the subscription has switched to the live execution data stream.
+	CatchUpPhaseLive
+)
+
+func (p CatchUpPhase) String() string {
+	switch p {
+	case CatchUpPhaseBackfill:
+		return "backfill"
+	case CatchUpPhaseLive:
+		return "live"
+	default:
+		return fmt.Sprintf("unknown(%d)", int32(p))
+	}
+}
+
+type CatchUpConfig struct {
+	// Backfill configures the backfill phase. StartHeight, EndHeight and Cursor are ignored.
+	Backfill BackfillConfig
+
+	// LiveThreshold is the maximum distance from the latest sealed height at which the
+	// subscription switches to the live stream. Defaults to 100.
+	LiveThreshold uint64
+
+	// OnPhaseChange is an optional callback called when the subscription changes phase.
+	OnPhaseChange func(phase CatchUpPhase, height uint64)
+}
+
+// CatchUpSubscription is a subscription that backfills historical heights before switching
+// to the live execution data stream.
+type CatchUpSubscription struct {
+	*Subscription[ExecutionDataResponse]
+
+	phase atomic.Int32
+}
+
+// Phase returns the current phase of the subscription.
+func (s *CatchUpSubscription) Phase() CatchUpPhase {
+	return CatchUpPhase(s.phase.Load())
+}
+
+// SubscribeExecutionDataWithCatchUp subscribes to execution data starting at the given height.
+//
+// Heights more than LiveThreshold blocks behind the latest sealed height are fetched concurrently
+// using Backfill. Once caught up, the subscription switches to SubscribeExecutionData starting at
+// the next undelivered height. Every height from startHeight onwards is delivered exactly once
+// and in order.
+func (c *ExecutionDataClient) SubscribeExecutionDataWithCatchUp(
+	ctx context.Context,
+	startHeight uint64,
+	config CatchUpConfig,
+	opts ...grpc.CallOption,
+) (*CatchUpSubscription, error) {
+	if startHeight == 0 {
+		return nil, fmt.Errorf("start height must be greater than 0")
+	}
+	if config.LiveThreshold == 0 {
+		config.LiveThreshold = defaultLiveThreshold
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	sub := &CatchUpSubscription{
+		Subscription: NewSubscription[ExecutionDataResponse](),
+	}
+	go func() {
+		defer close(sub.ch)
+		defer cancel()
+
+		next, err := c.catchUp(ctx, sub, startHeight, config, opts...)
+		if err != nil {
+			sub.err = err
+			return
+		}
+
+		sub.phase.Store(int32(CatchUpPhaseLive))
+		if config.OnPhaseChange != nil {
+			config.OnPhaseChange(CatchUpPhaseLive, next)
+		}
+
+		sub.err = c.followLive(ctx, sub, next, opts...)
+	}()
+
+	return sub, nil
+}
+
+// catchUp backfills heights until the next height is within the live threshold of the latest
+// sealed height. It returns the next height to deliver.
+func (c *ExecutionDataClient) catchUp(
+	ctx context.Context,
+	sub *CatchUpSubscription,
+	next uint64,
+	config CatchUpConfig,
+	opts ...grpc.CallOption,
+) (uint64, error) {
+	if config.OnPhaseChange != nil {
+		config.OnPhaseChange(CatchUpPhaseBackfill, next)
+	}
+
+	for {
+		latestSealed, err := c.GetLatestSealedHeight(ctx, opts...)
+		if err != nil {
+			return 0, err
+		}
+
+		if latestSealed < next || latestSealed-next < config.LiveThreshold {
+			return next, nil
+		}
+
+		backfillConfig := config.Backfill
+		backfillConfig.StartHeight = next
+		backfillConfig.EndHeight = latestSealed
+		backfillConfig.Cursor = nil
+
+		next, err = c.catchUpRange(ctx, sub, backfillConfig, opts...)
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// catchUpRange delivers the heights backfilled using the given config. It returns the next
+// height to deliver.
+func (c *ExecutionDataClient) catchUpRange(
+	ctx context.Context,
+	sub *CatchUpSubscription,
+	config BackfillConfig,
+	opts ...grpc.CallOption,
+) (uint64, error) {
+	// stop the backfill when returning early, so its goroutines don't leak
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	backfill, err := c.Backfill(ctx, config, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	next := config.StartHeight
+	for response := range backfill.Channel() {
+		if err := sub.send(ctx, response); err != nil {
+			return 0, err
+		}
+		next = response.Height + 1
+	}
+	if err := backfill.Err(); err != nil {
+		return 0, fmt.Errorf("error backfilling execution data: %w", err)
+	}
+
+	return next, nil
+}
+
+// followLive streams execution data starting at the given height, dropping any heights that
+// were already delivered and failing if a height is skipped.
+func (c *ExecutionDataClient) followLive(
+	ctx context.Context,
+	sub *CatchUpSubscription,
+	next uint64,
+	opts ...grpc.CallOption,
+) error {
+	// stop the live subscription when returning early, so its goroutine doesn't leak
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	live, err := c.SubscribeExecutionData(ctx, flow.ZeroID, next, opts...)
+	if err != nil {
+		return fmt.Errorf("could not subscribe to execution data: %w", err)
+	}
+
+	for response := range live.Channel() {
+		if response.Height < next {
+			continue
+		}
+		if response.Height > next {
+			return fmt.Errorf("live stream skipped from height %d to %d", next, response.Height)
+		}
+
+		if err := sub.send(ctx, response); err != nil {
+			return err
+		}
+		next++
+	}
+
+	return live.Err()
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeExecutionDataWithCatchUp(t *testing.T) {
+	t.Run("every height delivered once across the handoff", func(t *testing.T) {
+		node := newTestAccessNode(50)
+		c := newTestClient(node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		type phaseChange struct {
+			phase  CatchUpPhase
+			height uint64
+		}
+		var phases []phaseChange
+
+		sub, err := c.SubscribeExecutionDataWithCatchUp(ctx, 5, CatchUpConfig{
+			Backfill:      BackfillConfig{Concurrency: 4},
+			LiveThreshold: 10,
+			OnPhaseChange: func(phase CatchUpPhase, height uint64) {
+				phases = append(phases, phaseChange{phase, height})
+			},
+		})
+		require.NoError(t, err)
+
+		next := uint64(5)
+		for response := range sub.Channel() {
+			require.Equal(t, next, response.Height, "heights must be delivered once and in order")
+			assert.Equal(t, testBlockID(response.Height, 0), response.ExecutionData.BlockID)
+
+			switch response.Height {
+			case 20:
+				// blocks sealed during the first backfill are backfilled next
+				node.setSealed(80)
+			case 70:
+				assert.Equal(t, CatchUpPhaseBackfill, sub.Phase())
+
+				// blocks sealed during the second backfill are within the live threshold
+				node.setSealed(90)
+			case 90:
+				assert.Equal(t, CatchUpPhaseLive, sub.Phase())
+				cancel()
+			}
+			next++
+		}
+		assert.Equal(t, uint64(91), next)
+
+		assert.Equal(t, []phaseChange{
+			{CatchUpPhaseBackfill, 5},
+			{CatchUpPhaseLive, 81},
+		}, phases)
+
+		// only the live phase uses a stream, starting at the first height not backfilled
+		assert.Equal(t, []uint64{81}, node.subscriptions)
+	})
+
+	t.Run("start height within the live threshold", func(t *testing.T) {
+		node := newTestAccessNode(50)
+		c := newTestClient(node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := c.SubscribeExecutionDataWithCatchUp(ctx, 45, CatchUpConfig{LiveThreshold: 10})
+		require.NoError(t, err)
+
+		response := <-sub.Channel()
+		assert.Equal(t, uint64(45), response.Height)
+		assert.Equal(t, CatchUpPhaseLive, sub.Phase())
+		assert.Zero(t, node.callCount("GetExecutionDataByBlockID"))
+	})
+
+	t.Run("start height required", func(t *testing.T) {
+		c := newTestClient(newTestAccessNode(50))
+
+		_, err := c.SubscribeExecutionDataWithCatchUp(context.Background(), 0, CatchUpConfig{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,299 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/onflow/flow-go/model/flow"
+	"google.golang.org/grpc"
+)
+
+// CheckpointStore records the last fully processed height for named consumers.
+type CheckpointStore interface {
+	// Load returns the last processed height for the consumer. ok is false if no checkpoint
+	// has been stored yet.
+	Load(ctx context.Context, consumer string) (height uint64, ok bool, err error)
+
+	// Save records the last processed height for the consumer.
+	Save(ctx context.Context, consumer string, height uint64) error
+}
+
+// FileCheckpointStore is a CheckpointStore that keeps all checkpoints in a single JSON file.
+// Updates are written to a temporary file and renamed into place so the file is never left
+// partially written.
+type FileCheckpointStore struct {
+	mu          sync.Mutex
+	path        string
+	checkpoints map[string]uint64
+}
+
+var _ CheckpointStore = (*FileCheckpointStore)(nil)
+
+// NewFileCheckpointStore returns a FileCheckpointStore backed by the file at the given path.
+// The file is created on the first save if it does not exist.
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	s := &FileCheckpointStore{
+		path:        path,
+		checkpoints: make(map[string]uint64),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read checkpoint file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.checkpoints); err != nil {
+		return nil, fmt.Errorf("could not decode checkpoint file: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *FileCheckpointStore) Load(_ context.Context, consumer string) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	height, ok := s.checkpoints[consumer]
+	return height, ok, nil
+}
+
+func (s *FileCheckpointStore) Save(_ context.Context, consumer string, height uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.checkpoints[consumer]
+	s.checkpoints[consumer] = height
+
+	data, err := json.Marshal(s.checkpoints)
+	if err == nil {
+		err = writeFileAtomic(s.path, data)
+	}
+	if err != nil {
+		// keep the in-memory state consistent with the file
+		if ok {
+			s.checkpoints[consumer] = previous
+		} else {
+			delete(s.checkpoints, consumer)
+		}
+		return fmt.Errorf("could not write checkpoint file: %w", err)
+	}
+
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// BadgerCheckpointStore is a CheckpointStore backed by an embedded badger database.
+type BadgerCheckpointStore struct {
+	db *badger.DB
+}
+
+var _ CheckpointStore = (*BadgerCheckpointStore)(nil)
+
+// NewBadgerCheckpointStore returns a BadgerCheckpointStore using the given database. The caller
+// is responsible for closing the database.
+func NewBadgerCheckpointStore(db *badger.DB) *BadgerCheckpointStore {
+	return &BadgerCheckpointStore{db: db}
+}
+
+func (s *BadgerCheckpointStore) Load(_ context.Context, consumer string) (uint64, bool, error) {
+	var height uint64
+	var ok bool
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(checkpointKey(consumer))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		return item.Value(func(val []byte) error {
+			if len(val) != 8 {
+				return fmt.Errorf("invalid checkpoint value length: %d", len(val))
+			}
+			height = binary.BigEndian.Uint64(val)
+			ok = true
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("could not load checkpoint for %s: %w", consumer, err)
+	}
+
+	return height, ok, nil
+}
+
+func (s *BadgerCheckpointStore) Save(_ context.Context, consumer string, height uint64) error {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(checkpointKey(consumer), binary.BigEndian.AppendUint64(nil, height))
+	})
+	if err != nil {
+		return fmt.Errorf("could not save checkpoint for %s: %w", consumer, err)
+	}
+
+	return nil
+}
+
+func checkpointKey(consumer string) []byte {
+	return []byte("checkpoint/" + consumer)
+}
+
+// CheckpointSubscription is a subscription that resumes from a consumer's stored checkpoint.
+// Consumers must call Commit once they have fully processed a response.
+type CheckpointSubscription[T any] struct {
+	*Subscription[T]
+
+	store    CheckpointStore
+	consumer string
+
+	mu        sync.Mutex
+	committed uint64
+}
+
+// Commit records that all heights up to and including the given height have been processed.
+// Heights at or below the last committed height are ignored.
+func (s *CheckpointSubscription[T]) Commit(ctx context.Context, height uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if height <= s.committed {
+		return nil
+	}
+
+	if err := s.store.Save(ctx, s.consumer, height); err != nil {
+		return err
+	}
+	s.committed = height
+
+	return nil
+}
+
+// SubscribeEventsFromCheckpoint subscribes to events starting at the height after the consumer's
+// stored checkpoint. If no checkpoint exists, it starts at defaultStartHeight (0 for the latest block).
+func (c *ExecutionDataClient) SubscribeEventsFromCheckpoint(
+	ctx context.Context,
+	store CheckpointStore,
+	consumer string,
+	defaultStartHeight uint64,
+	filter EventFilter,
+	opts ...grpc.CallOption,
+) (*CheckpointSubscription[EventsResponse], error) {
+	startHeight, committed, err := checkpointStartHeight(ctx, store, consumer, defaultStartHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := c.SubscribeEvents(ctx, flow.ZeroID, startHeight, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckpointSubscription[EventsResponse]{
+		Subscription: sub,
+		store:        store,
+		consumer:     consumer,
+		committed:    committed,
+	}, nil
+}
+
+// SubscribeExecutionDataFromCheckpoint subscribes to execution data starting at the height after
+// the consumer's stored checkpoint. If no checkpoint exists, it starts at defaultStartHeight
+// (0 for the latest block).
+func (c *ExecutionDataClient) SubscribeExecutionDataFromCheckpoint(
+	ctx context.Context,
+	store CheckpointStore,
+	consumer string,
+	defaultStartHeight uint64,
+	opts ...grpc.CallOption,
+) (*CheckpointSubscription[ExecutionDataResponse], error) {
+	startHeight, committed, err := checkpointStartHeight(ctx, store, consumer, defaultStartHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := c.SubscribeExecutionData(ctx, flow.ZeroID, startHeight, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckpointSubscription[ExecutionDataResponse]{
+		Subscription: sub,
+		store:        store,
+		consumer:     consumer,
+		committed:    committed,
+	}, nil
+}
+
+func checkpointStartHeight(
+	ctx context.Context,
+	store CheckpointStore,
+	consumer string,
+	defaultStartHeight uint64,
+) (startHeight uint64, committed uint64, err error) {
+	height, ok, err := store.Load(ctx, consumer)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not load checkpoint for %s: %w", consumer, err)
+	}
+	if !ok {
+		return defaultStartHeight, 0, nil
+	}
+
+	return height + 1, height, nil
+}
+
+// CheckpointCursor adapts a CheckpointStore to a BackfillCursor for the given consumer, so
+// backfills and subscriptions can share checkpoints.
+func CheckpointCursor(store CheckpointStore, consumer string) BackfillCursor {
+	return &checkpointCursor{store: store, consumer: consumer}
+}
+
+type checkpointCursor struct {
+	store    CheckpointStore
+	consumer string
+}
+
+func (c *checkpointCursor) Load(ctx context.Context) (uint64, bool, error) {
+	height, ok, err := c.store.Load(ctx, c.consumer)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	return height + 1, true, nil
+}
+
+func (c *checkpointCursor) Store(ctx context.Context, height uint64) error {
+	if height == 0 {
+		return nil
+	}
+	return c.store.Save(ctx, c.consumer, height-1)
+}
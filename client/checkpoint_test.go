@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("missing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoints.json")
+
+		s, err := NewFileCheckpointStore(path)
+		require.NoError(t, err)
+
+		_, ok, err := s.Load(ctx, "consumer")
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		// the file is only created on the first save
+		_, err = os.Stat(path)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("saved checkpoints reloaded", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "checkpoints.json")
+
+		s, err := NewFileCheckpointStore(path)
+		require.NoError(t, err)
+		require.NoError(t, s.Save(ctx, "a", 10))
+		require.NoError(t, s.Save(ctx, "b", 20))
+		require.NoError(t, s.Save(ctx, "a", 11))
+
+		s, err = NewFileCheckpointStore(path)
+		require.NoError(t, err)
+		for consumer, expected := range map[string]uint64{"a": 11, "b": 20} {
+			height, ok, err := s.Load(ctx, consumer)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, expected, height)
+		}
+
+		// writes are renamed into place, so no temporary files are left behind
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "checkpoints.json", entries[0].Name())
+	})
+
+	t.Run("corrupt file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoints.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"a": 1`), 0o600))
+
+		_, err := NewFileCheckpointStore(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("failed write leaves state unchanged", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "checkpoints.json")
+
+		s, err := NewFileCheckpointStore(path)
+		require.NoError(t, err)
+		require.NoError(t, s.Save(ctx, "a", 10))
+
+		// the temporary file can't be created once the directory is gone
+		require.NoError(t, os.RemoveAll(dir))
+		require.Error(t, s.Save(ctx, "a", 11))
+		require.Error(t, s.Save(ctx, "b", 1))
+
+		height, ok, err := s.Load(ctx, "a")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(10), height)
+
+		_, ok, err = s.Load(ctx, "b")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestBadgerCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := NewBadgerCheckpointStore(db)
+
+	_, ok, err := s.Load(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, s.Save(ctx, "a", 10))
+	require.NoError(t, s.Save(ctx, "b", 1<<40))
+	require.NoError(t, s.Save(ctx, "a", 11))
+
+	for consumer, expected := range map[string]uint64{"a": 11, "b": 1 << 40} {
+		height, ok, err := s.Load(ctx, consumer)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expected, height)
+	}
+
+	t.Run("invalid value", func(t *testing.T) {
+		require.NoError(t, db.Update(func(txn *badger.Txn) error {
+			return txn.Set(checkpointKey("c"), []byte{1, 2, 3})
+		}))
+
+		_, _, err := s.Load(ctx, "c")
+		assert.Error(t, err)
+	})
+}
+
+func TestCheckpointSubscription(t *testing.T) {
+	newStore := func(t *testing.T) *FileCheckpointStore {
+		s, err := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("resumes after the checkpoint", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		c := newTestClient(node)
+		store := newStore(t)
+		require.NoError(t, store.Save(context.Background(), "consumer", 41))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := c.SubscribeExecutionDataFromCheckpoint(ctx, store, "consumer", 1)
+		require.NoError(t, err)
+
+		response := <-sub.Channel()
+		assert.Equal(t, uint64(42), response.Height)
+		assert.Equal(t, []uint64{42}, node.subscriptions)
+	})
+
+	t.Run("starts at the default without a checkpoint", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		c := newTestClient(node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := c.SubscribeEventsFromCheckpoint(ctx, newStore(t), "consumer", 7, EventFilter{})
+		require.NoError(t, err)
+
+		response := <-sub.Channel()
+		assert.Equal(t, uint64(7), response.Height)
+		assert.Equal(t, []uint64{7}, node.subscriptions)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		require.NoError(t, store.Save(ctx, "consumer", 10))
+
+		node := newTestAccessNode(100)
+		c := newTestClient(node)
+
+		subCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		sub, err := c.SubscribeEventsFromCheckpoint(subCtx, store, "consumer", 1, EventFilter{})
+		require.NoError(t, err)
+
+		require.NoError(t, sub.Commit(ctx, 12))
+
+		// heights at or below the last commit, including the resumed checkpoint, are ignored
+		require.NoError(t, sub.Commit(ctx, 11))
+		height, _, err := store.Load(ctx, "consumer")
+		require.NoError(t, err)
+		assert.Equal(t, uint64(12), height)
+
+		// a resumed subscription starts after the last commit
+		cancel()
+		subCtx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		sub, err = c.SubscribeEventsFromCheckpoint(subCtx, store, "consumer", 1, EventFilter{})
+		require.NoError(t, err)
+		require.NoError(t, sub.Commit(ctx, 12))
+		assert.Equal(t, []uint64{11, 13}, node.subscriptions)
+	})
+
+	t.Run("backfill cursor shares checkpoints", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		require.NoError(t, store.Save(ctx, "consumer", 14))
+
+		c := newTestClient(newTestAccessNode(100))
+		heights, err := backfillHeights(t, c, BackfillConfig{
+			StartHeight: 10,
+			EndHeight:   20,
+			Cursor:      CheckpointCursor(store, "consumer"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{15, 16, 17, 18, 19, 20}, heights)
+
+		// the checkpoint is the last processed height
+		height, _, err := store.Load(ctx, "consumer")
+		require.NoError(t, err)
+		assert.Equal(t, uint64(20), height)
+	})
+}
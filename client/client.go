@@ -4,56 +4,197 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/onflow/flow-go/engine/common/rpc/convert"
 	"github.com/onflow/flow-go/model/flow"
 	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
 	executiondata "github.com/onflow/flow/protobuf/go/flow/executiondata"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type ExecutionDataClient struct {
-	client executiondata.ExecutionDataAPIClient
-	chain  flow.Chain
+	conn         *grpc.ClientConn
+	client       executiondata.ExecutionDataAPIClient
+	accessClient access.AccessAPIClient
+	chain        flow.Chain
+	log          Logger
+	metrics      Metrics
+	tracer       trace.Tracer
+
+	verifyExecutionData bool
+	verifyEvents        bool
+	guard               GuardConfig
+	heartbeat           HeartbeatConfig
+
+	blockIDs *blockIDCache
+
+	// lookup fetches the execution data needed internally, e.g. by backfills, gap repairs and
+	// verification. nil uses the client itself.
+	lookup executionDataLookup
 }
 
+// executionDataLookup fetches execution data for a block. It's implemented by
+// ExecutionDataClient and CachingClient.
+type executionDataLookup interface {
+	GetExecutionDataForBlockID(ctx context.Context, blockID flow.Identifier, opts ...grpc.CallOption) (*execution_data.BlockExecutionData, error)
+	GetExecutionDataByHeight(ctx context.Context, height uint64, opts ...grpc.CallOption) (*execution_data.BlockExecutionData, error)
+}
+
+// executionData returns the lookup used for execution data fetched internally.
+func (c *ExecutionDataClient) executionData() executionDataLookup {
+	if c.lookup != nil {
+		return c.lookup
+	}
+	return c
+}
+
+// NewExecutionDataClient returns a client connected to the access node at the given address using
+// the given dial options. If none are provided, insecure transport credentials are used.
+//
+// Use NewExecutionDataClientWithOptions to configure the client's other options.
 func NewExecutionDataClient(address string, chain flow.Chain, opts ...grpc.DialOption) (*ExecutionDataClient, error) {
-	if len(opts) == 0 {
-		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	return NewExecutionDataClientWithOptions(address, chain, WithDialOptions(opts...))
+}
+
+// NewExecutionDataClientWithOptions returns a client connected to the access node at the given
+// address, configured with the given options.
+func NewExecutionDataClientWithOptions(address string, chain flow.Chain, opts ...Option) (*ExecutionDataClient, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
 	}
 
-	conn, err := grpc.Dial(address, opts...)
+	// typed options come first so that raw dial options override them
+	dialOpts := cfg.grpcDialOptions()
+	switch {
+	case cfg.tlsConfig != nil:
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(cfg.tlsConfig)))
+	case len(cfg.dialOptions) == 0:
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if auth := cfg.authCredentials(); auth != nil {
+		if auth.requireTLS && cfg.tlsConfig == nil && len(cfg.dialOptions) == 0 {
+			return nil, fmt.Errorf("credentials require TLS, use WithTLS or WithInsecureAuth")
+		}
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth))
+	}
+	dialOpts = append(dialOpts, cfg.dialOptions...)
+	if cfg.propagator != nil {
+		dialOpts = append(dialOpts,
+			grpc.WithChainUnaryInterceptor(tracingUnaryInterceptor(cfg.propagator)),
+			grpc.WithChainStreamInterceptor(tracingStreamInterceptor(cfg.propagator)),
+		)
+	}
+
+	conn, err := grpc.Dial(address, dialOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &ExecutionDataClient{
-		client: executiondata.NewExecutionDataAPIClient(conn),
-		chain:  chain,
+		conn:         conn,
+		client:       executiondata.NewExecutionDataAPIClient(conn),
+		accessClient: access.NewAccessAPIClient(conn),
+		chain:        chain,
+		log:          cfg.logger,
+		metrics:      cfg.metrics,
+		tracer:       cfg.tracerProvider.Tracer(tracerName),
+		blockIDs:     newBlockIDCache(defaultBlockIDCacheSize),
+
+		verifyExecutionData: cfg.verifyExecutionData,
+		verifyEvents:        cfg.verifyEvents,
+		guard:               cfg.guard,
+		heartbeat:           cfg.heartbeat,
 	}, nil
 }
 
+// Close closes the connection to the access node. Active subscriptions fail.
+func (c *ExecutionDataClient) Close() error {
+	return c.conn.Close()
+}
+
 // GetExecutionDataForBlockID returns the BlockExecutionData for the given block ID.
 func (c *ExecutionDataClient) GetExecutionDataForBlockID(
 	ctx context.Context,
 	blockID flow.Identifier,
 	opts ...grpc.CallOption,
 ) (*execution_data.BlockExecutionData, error) {
+	ctx, span := c.tracer.Start(ctx, "ExecutionDataClient.GetExecutionDataForBlockID",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(blockSpanAttributes(0, blockID)...),
+	)
+	defer span.End()
+
+	m, err := c.getExecutionDataMessage(ctx, blockID, opts...)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	execData, err := c.executionDataFromMessage(ctx, m, opts...)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attrChunks.Int(len(execData.ChunkExecutionDatas)),
+		attrEvents.Int(countEvents(execData)),
+		attrPayloadSize.Int(proto.Size(m)),
+	)
+
+	return execData, nil
+}
+
+// getExecutionDataMessage returns the unconverted execution data message for the given block ID.
+func (c *ExecutionDataClient) getExecutionDataMessage(
+	ctx context.Context,
+	blockID flow.Identifier,
+	opts ...grpc.CallOption,
+) (*entities.BlockExecutionData, error) {
 	req := &executiondata.GetExecutionDataByBlockIDRequest{
-		BlockId: blockID[:],
+		BlockId:              blockID[:],
+		EventEncodingVersion: c.eventEncoding(),
 	}
 	resp, err := c.client.GetExecutionDataByBlockID(ctx, req, opts...)
 	if err != nil {
+		c.metrics.Error("get_execution_data", err)
+		if status.Code(err) == codes.NotFound {
+			return nil, &NotAvailableError{BlockID: blockID, Err: err}
+		}
 		return nil, err
 	}
 
-	execData, err := convert.MessageToBlockExecutionData(resp.GetBlockExecutionData(), c.chain)
+	return resp.GetBlockExecutionData(), nil
+}
+
+// executionDataFromMessage converts the execution data message, and verifies it if enabled.
+func (c *ExecutionDataClient) executionDataFromMessage(
+	ctx context.Context,
+	m *entities.BlockExecutionData,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	execData, err := c.convertExecutionData(ctx, streamExecutionData, m)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.verifyExecutionData {
+		if err := c.VerifyExecutionData(ctx, execData, opts...); err != nil {
+			c.metrics.Error("verify_execution_data", err)
+			return nil, err
+		}
+	}
+
 	return execData, nil
 }
 
@@ -61,60 +202,70 @@ type ExecutionDataResponse struct {
 	BlockID       flow.Identifier
 	Height        uint64
 	ExecutionData *execution_data.BlockExecutionData
+
+	receiveSpan
+}
+
+func (r ExecutionDataResponse) GetHeight() uint64 {
+	return r.Height
+}
+
+func (r ExecutionDataResponse) GetBlockID() flow.Identifier {
+	return r.BlockID
 }
 
 // SubscribeExecutionData subscribes to execution data updates starting at the given block ID or height.
+//
+// It's built on SubscribeLazyExecutionData, fully converting each block before it's delivered.
 func (c *ExecutionDataClient) SubscribeExecutionData(
 	ctx context.Context,
 	startBlockID flow.Identifier,
 	startHeight uint64,
 	opts ...grpc.CallOption,
 ) (*Subscription[ExecutionDataResponse], error) {
-	if startBlockID != flow.ZeroID && startHeight > 0 {
-		return nil, fmt.Errorf("cannot specify both start block ID and start height")
-	}
-
-	req := executiondata.SubscribeExecutionDataRequest{}
-	if startBlockID != flow.ZeroID {
-		req.StartBlockId = startBlockID[:]
-	}
-	if startHeight > 0 {
-		req.StartBlockHeight = startHeight
-	}
+	ctx, cancel := context.WithCancel(ctx)
 
-	stream, err := c.client.SubscribeExecutionData(ctx, &req, opts...)
+	lazy, err := c.SubscribeLazyExecutionData(ctx, startBlockID, startHeight, opts...)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
+	// the lazy subscription records the stream's metrics, including the time spent converting
+	// and waiting for the consumer
 	sub := NewSubscription[ExecutionDataResponse]()
+
 	go func() {
 		defer close(sub.ch)
+		defer cancel()
 
-		for {
-			resp, err := stream.Recv()
-			if err == io.EOF {
-				return
-			}
+		for response := range lazy.Channel() {
+			execData, err := c.convertLazyExecutionData(response.TraceContext(ctx), streamExecutionData, response.ExecutionData)
 			if err != nil {
-				sub.err = fmt.Errorf("error receiving execution data: %w", err)
-				return
-			}
-
-			execData, err := convert.MessageToBlockExecutionData(resp.GetBlockExecutionData(), c.chain)
-			if err != nil {
-				log.Printf("error converting execution data:\n%v", resp.GetBlockExecutionData())
+				c.log.Error("error converting execution data",
+					F("subscription_id", lazy.ID()),
+					F("height", response.Height),
+					F("block_id", response.BlockID),
+					F("chunks", response.ExecutionData.NumChunks()),
+					F("error", err),
+				)
 				sub.err = fmt.Errorf("error converting execution data: %w", err)
 				return
 			}
 
-			log.Printf("received execution data for block %d %x with %d chunks", resp.BlockHeight, execData.BlockID, len(execData.ChunkExecutionDatas))
-
-			sub.ch <- ExecutionDataResponse{
-				Height:        resp.BlockHeight,
+			err = sub.send(ctx, ExecutionDataResponse{
+				BlockID:       response.BlockID,
+				Height:        response.Height,
 				ExecutionData: execData,
+				receiveSpan:   response.receiveSpan,
+			})
+			if err != nil {
+				sub.err = err
+				return
 			}
 		}
+
+		sub.err = lazy.Err()
 	}()
 
 	return sub, nil
@@ -130,6 +281,22 @@ type EventsResponse struct {
 	Height  uint64
 	BlockID flow.Identifier
 	Events  []flow.Event
+
+	receiveSpan
+}
+
+func (r EventsResponse) GetHeight() uint64 {
+	return r.Height
+}
+
+func (r EventsResponse) GetBlockID() flow.Identifier {
+	return r.BlockID
+}
+
+// IsHeartbeat returns true if the response contains no events. The access node sends these
+// periodically so consumers of filtered subscriptions can track progress.
+func (r EventsResponse) IsHeartbeat() bool {
+	return len(r.Events) == 0
 }
 
 func (c *ExecutionDataClient) SubscribeEvents(
@@ -143,44 +310,141 @@ func (c *ExecutionDataClient) SubscribeEvents(
 		return nil, fmt.Errorf("cannot specify both start block ID and start height")
 	}
 
-	req := executiondata.SubscribeEventsRequest{
-		Filter: &executiondata.EventFilter{
-			EventType: filter.EventTypes,
-			Address:   filter.Addresses,
-			Contract:  filter.Contracts,
-		},
-	}
-	if startBlockID != flow.ZeroID {
-		req.StartBlockId = startBlockID[:]
-	}
-	if startHeight > 0 {
-		req.StartBlockHeight = startHeight
+	if err := filter.Validate(c.chain); err != nil {
+		return nil, fmt.Errorf("invalid event filter: %w", err)
 	}
 
-	stream, err := c.client.SubscribeEvents(ctx, &req, opts...)
+	// next is the height after the last received response, used to resume after a stall
+	var next uint64
+
+	stream, err := newWatchedStream(ctx, c, streamEvents,
+		func(ctx context.Context, resume bool) (streamReceiver[*executiondata.SubscribeEventsResponse], error) {
+			req := executiondata.SubscribeEventsRequest{
+				Filter: &executiondata.EventFilter{
+					EventType: filter.EventTypes,
+					Address:   filter.Addresses,
+					Contract:  filter.Contracts,
+				},
+				HeartbeatInterval:    c.heartbeat.Interval,
+				EventEncodingVersion: c.eventEncoding(),
+			}
+			switch {
+			case resume && next > 0:
+				req.StartBlockHeight = next
+			case startBlockID != flow.ZeroID:
+				req.StartBlockId = startBlockID[:]
+			case startHeight > 0:
+				req.StartBlockHeight = startHeight
+			}
+
+			stream, err := c.client.SubscribeEvents(ctx, &req, opts...)
+			if err != nil {
+				c.metrics.Error("subscribe_events", err)
+				return nil, err
+			}
+			return stream, nil
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	sub := NewSubscription[EventsResponse]()
+	sub := newStreamSubscription[EventsResponse](streamEvents, c.metrics)
+	c.log.Debug("subscribed to events",
+		F("subscription_id", sub.ID()),
+		F("start_block_id", startBlockID),
+		F("start_height", startHeight),
+	)
+
+	guard := c.newEventsGuard()
+
 	go func() {
 		defer close(sub.ch)
+		defer stream.close()
 
 		for {
 			resp, err := stream.Recv()
 			if err == io.EOF {
+				c.log.Debug("events stream closed", F("subscription_id", sub.ID()))
 				return
 			}
 			if err != nil {
+				c.log.Warn("error receiving events", F("subscription_id", sub.ID()), F("error", err))
+				c.metrics.Error("subscribe_events", err)
 				sub.err = fmt.Errorf("error receiving execution data: %w", err)
 				return
 			}
 
-			sub.ch <- EventsResponse{
-				Height:  resp.GetBlockHeight(),
-				BlockID: convert.MessageToIdentifier(resp.GetBlockId()),
-				Events:  convert.MessagesToEvents(resp.GetEvents()),
+			blockID := convert.MessageToIdentifier(resp.GetBlockId())
+			_, span := c.tracer.Start(ctx, "EventsSubscription.Receive",
+				trace.WithAttributes(blockSpanAttributes(resp.GetBlockHeight(), blockID)...),
+				trace.WithAttributes(
+					attrEvents.Int(len(resp.GetEvents())),
+					attrPayloadSize.Int(proto.Size(resp)),
+				),
+			)
+
+			start := time.Now()
+			response := EventsResponse{
+				Height:      resp.GetBlockHeight(),
+				BlockID:     blockID,
+				Events:      convert.MessagesToEvents(resp.GetEvents()),
+				receiveSpan: receiveSpan{spanContext: span.SpanContext()},
+			}
+			c.metrics.ConversionDuration(streamEvents, time.Since(start))
+			c.metrics.BlockReceived(streamEvents, len(response.Events), proto.Size(resp))
+
+			if c.verifyEvents {
+				err := c.VerifyEvents(ctx, response, filter, opts...)
+				if err == nil && next > 0 && response.Height > next {
+					// the skipped heights must not contain matching events, otherwise they were withheld
+					err = c.VerifyNoEvents(ctx, next, response.Height-1, filter, opts...)
+				}
+				if err != nil {
+					recordSpanError(span, err)
+					span.End()
+					c.log.Error("events failed verification",
+						F("subscription_id", sub.ID()),
+						F("height", response.Height),
+						F("block_id", response.BlockID),
+						F("error", err),
+					)
+					c.metrics.Error("verify_events", err)
+					sub.err = fmt.Errorf("error verifying events: %w", err)
+					return
+				}
+			}
+
+			c.log.Debug("received events",
+				F("subscription_id", sub.ID()),
+				F("height", response.Height),
+				F("block_id", response.BlockID),
+				F("events", len(response.Events)),
+			)
+
+			responses, err := guard.check(ctx, response)
+			if err != nil {
+				recordSpanError(span, err)
+				span.End()
+				c.log.Error("events subscription failed validation", F("subscription_id", sub.ID()), F("error", err))
+				sub.err = err
+				return
+			}
+
+			next = response.Height + 1
+
+			for _, response := range responses {
+				if c.heartbeat.SuppressHeartbeats && response.IsHeartbeat() {
+					continue
+				}
+				if err := sub.send(ctx, response); err != nil {
+					span.End()
+					sub.err = err
+					return
+				}
+				c.metrics.DeliveredHeight(streamEvents, response.Height)
 			}
+			span.End()
 		}
 	}()
 
@@ -0,0 +1,416 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"go.uber.org/multierr"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultConsensusTimeout      = 30 * time.Second
+	defaultConsensusPollInterval = time.Second
+	divergenceBufferSize         = 100
+)
+
+type ConsensusConfig struct {
+	// Quorum is the number of nodes that must return identical execution data before a block is
+	// released. Defaults to a majority of the configured nodes.
+	Quorum int
+
+	// Timeout is the maximum time to wait for each node to respond. Defaults to 30s.
+	Timeout time.Duration
+
+	// PollInterval is the interval between attempts to fetch a height that is not available yet
+	// when subscribing. Defaults to 1s.
+	PollInterval time.Duration
+}
+
+// Divergence describes how the execution data returned by a node differs from the data agreed
+// on by the quorum.
+type Divergence struct {
+	Height  uint64
+	Address string
+
+	// Differences lists each field that differs from the quorum's data.
+	Differences []string
+}
+
+func (d *Divergence) Error() string {
+	return fmt.Sprintf("access node %s diverged at height %d: %s", d.Address, d.Height, strings.Join(d.Differences, "; "))
+}
+
+// ConsensusClient fetches execution data from multiple access nodes, and only returns a block's
+// data once a quorum of nodes returned identical data.
+type ConsensusClient struct {
+	addresses   []string
+	clients     []*ExecutionDataClient
+	config      ConsensusConfig
+	log         Logger
+	divergences chan *Divergence
+}
+
+// NewConsensusClient returns a ConsensusClient connected to each of the given addresses. The options
+// are applied to the client for each node.
+func NewConsensusClient(
+	addresses []string,
+	chain flow.Chain,
+	config ConsensusConfig,
+	opts ...Option,
+) (*ConsensusClient, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("at least one address is required")
+	}
+	if config.Quorum <= 0 {
+		config.Quorum = len(addresses)/2 + 1
+	}
+	if config.Quorum > len(addresses) {
+		return nil, fmt.Errorf("quorum %d is larger than the number of nodes %d", config.Quorum, len(addresses))
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultConsensusTimeout
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultConsensusPollInterval
+	}
+
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	clients := make([]*ExecutionDataClient, 0, len(addresses))
+	for _, address := range addresses {
+		client, err := NewExecutionDataClientWithOptions(address, chain, opts...)
+		if err != nil {
+			for _, c := range clients {
+				_ = c.Close()
+			}
+			return nil, fmt.Errorf("could not create client for %s: %w", address, err)
+		}
+		clients = append(clients, client)
+	}
+
+	return &ConsensusClient{
+		addresses:   addresses,
+		clients:     clients,
+		config:      config,
+		log:         cfg.logger,
+		divergences: make(chan *Divergence, divergenceBufferSize),
+	}, nil
+}
+
+// Close closes the connections to the access nodes.
+func (c *ConsensusClient) Close() error {
+	var errs error
+	for i, client := range c.clients {
+		if err := client.Close(); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%s: %w", c.addresses[i], err))
+		}
+	}
+	return errs
+}
+
+// Divergences returns a channel that receives a Divergence for each node whose data did not
+// match the quorum. If the channel is not drained, new divergences are logged and dropped.
+func (c *ConsensusClient) Divergences() <-chan *Divergence {
+	return c.divergences
+}
+
+type nodeExecutionData struct {
+	address  string
+	execData *execution_data.BlockExecutionData
+	digest   *executionDataDigest
+	err      error
+}
+
+// GetExecutionDataByHeight fetches the execution data for the sealed block at the given height
+// from all nodes, and returns it as soon as a quorum of nodes agree. Requests to the remaining
+// nodes are cancelled.
+//
+// A *NotAvailableError is returned if no quorum was reached, but enough nodes failed with a
+// transient error, e.g. the data is not available yet or the node is unavailable, that one may
+// be reached when retried.
+func (c *ConsensusClient) GetExecutionDataByHeight(
+	ctx context.Context,
+	height uint64,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so requests completing after a quorum is reached don't block
+	results := make(chan nodeExecutionData, len(c.clients))
+	for i, client := range c.clients {
+		go func(i int, client *ExecutionDataClient) {
+			ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+			defer cancel()
+
+			result := nodeExecutionData{address: c.addresses[i]}
+			result.execData, result.err = client.GetExecutionDataByHeight(ctx, height, opts...)
+			if result.err == nil {
+				result.digest, result.err = digestExecutionData(ctx, result.execData)
+			}
+			results <- result
+		}(i, client)
+	}
+
+	// group nodes by the data they returned
+	groups := make(map[string][]nodeExecutionData)
+	var errs error
+	transient := 0
+	for range c.clients {
+		result := <-results
+		if result.err != nil {
+			if isTransient(result.err) {
+				transient++
+			}
+			errs = multierr.Append(errs, fmt.Errorf("%s: %w", result.address, result.err))
+			continue
+		}
+
+		key := result.digest.key()
+		groups[key] = append(groups[key], result)
+
+		if agreed := groups[key]; len(agreed) >= c.config.Quorum {
+			c.reportDivergences(height, agreed[0].digest, groups)
+			return agreed[0].execData, nil
+		}
+	}
+
+	// report every node against the largest group so operators can see what differed
+	var largest []nodeExecutionData
+	for _, group := range groups {
+		if len(group) > len(largest) {
+			largest = group
+		}
+	}
+	if largest != nil {
+		c.reportDivergences(height, largest[0].digest, groups)
+	}
+
+	// nodes that failed transiently may still agree with the largest group when retried
+	if len(largest)+transient >= c.config.Quorum {
+		return nil, &NotAvailableError{Height: height, Err: errs}
+	}
+	return nil, fmt.Errorf("no quorum of %d nodes agreed on execution data for height %d (largest group: %d): %w",
+		c.config.Quorum, height, len(largest), errs)
+}
+
+func (c *ConsensusClient) reportDivergences(height uint64, expected *executionDataDigest, groups map[string][]nodeExecutionData) {
+	expectedKey := expected.key()
+	for key, group := range groups {
+		if key == expectedKey {
+			continue
+		}
+		for _, result := range group {
+			divergence := &Divergence{
+				Height:      height,
+				Address:     result.address,
+				Differences: expected.diff(result.digest),
+			}
+
+			select {
+			case c.divergences <- divergence:
+			default:
+				c.log.Warn("divergence channel full, dropping divergence", F("error", divergence))
+			}
+		}
+	}
+}
+
+// Subscribe returns a subscription that delivers execution data verified by a quorum of nodes
+// for each height, starting at startHeight. Heights are retried every PollInterval while no
+// quorum is reached because of a *NotAvailableError.
+func (c *ConsensusClient) Subscribe(
+	ctx context.Context,
+	startHeight uint64,
+	opts ...grpc.CallOption,
+) (*Subscription[ExecutionDataResponse], error) {
+	if startHeight == 0 {
+		return nil, fmt.Errorf("start height must be greater than 0")
+	}
+
+	sub := NewSubscription[ExecutionDataResponse]()
+	go func() {
+		defer close(sub.ch)
+
+		for height := startHeight; ; {
+			execData, err := c.GetExecutionDataByHeight(ctx, height, opts...)
+			if err != nil {
+				if ctx.Err() != nil {
+					sub.err = ctx.Err()
+					return
+				}
+				if !IsNotAvailable(err) {
+					sub.err = err
+					return
+				}
+
+				select {
+				case <-ctx.Done():
+					sub.err = ctx.Err()
+					return
+				case <-time.After(c.config.PollInterval):
+				}
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				sub.err = ctx.Err()
+				return
+			case sub.ch <- ExecutionDataResponse{
+				BlockID:       execData.BlockID,
+				Height:        height,
+				ExecutionData: execData,
+			}:
+			}
+			height++
+		}
+	}()
+
+	return sub, nil
+}
+
+// executionDataDigest summarizes the execution data compared between nodes. The ID covers all of
+// the execution data, and the chunk digests describe where it differs.
+type executionDataDigest struct {
+	blockID flow.Identifier
+	id      flow.Identifier
+	chunks  []chunkDigest
+}
+
+type chunkDigest struct {
+	collectionID   flow.Identifier
+	transactions   int
+	events         int
+	eventsHash     flow.Identifier
+	payloads       int
+	trieUpdateHash flow.Identifier
+}
+
+func digestExecutionData(ctx context.Context, execData *execution_data.BlockExecutionData) (*executionDataDigest, error) {
+	id, err := execution_data.CalculateID(ctx, execData, execution_data.DefaultSerializer)
+	if err != nil {
+		return nil, fmt.Errorf("could not calculate execution data ID: %w", err)
+	}
+
+	digest := &executionDataDigest{
+		blockID: execData.BlockID,
+		id:      id,
+		chunks:  make([]chunkDigest, 0, len(execData.ChunkExecutionDatas)),
+	}
+
+	for i, chunk := range execData.ChunkExecutionDatas {
+		eventsHash, err := flow.EventsMerkleRootHash(chunk.Events)
+		if err != nil {
+			return nil, fmt.Errorf("could not hash events for chunk %d: %w", i, err)
+		}
+
+		cd := chunkDigest{
+			events:     len(chunk.Events),
+			eventsHash: eventsHash,
+		}
+		if chunk.Collection != nil {
+			cd.collectionID = chunk.Collection.ID()
+			cd.transactions = len(chunk.Collection.Transactions)
+		}
+		if chunk.TrieUpdate != nil {
+			cd.payloads = len(chunk.TrieUpdate.Payloads)
+			cd.trieUpdateHash, err = hashTrieUpdate(chunk.TrieUpdate)
+			if err != nil {
+				return nil, fmt.Errorf("could not hash trie update for chunk %d: %w", i, err)
+			}
+		}
+		digest.chunks = append(digest.chunks, cd)
+	}
+
+	return digest, nil
+}
+
+// hashTrieUpdate hashes the trie update's root hash, paths and payloads, including the
+// payloads' values.
+func hashTrieUpdate(update *ledger.TrieUpdate) (flow.Identifier, error) {
+	h := sha256.New()
+
+	// writeBytes writes a length prefix before b, so adjacent fields can't be confused
+	writeBytes := func(b []byte) {
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+
+	h.Write(update.RootHash[:])
+	for i, path := range update.Paths {
+		h.Write(path[:])
+
+		payload := update.Payloads[i]
+		key, err := payload.Key()
+		if err != nil {
+			return flow.ZeroID, fmt.Errorf("could not decode key of payload %d: %w", i, err)
+		}
+		for _, part := range key.KeyParts {
+			var partType [2]byte
+			binary.BigEndian.PutUint16(partType[:], part.Type)
+			h.Write(partType[:])
+			writeBytes(part.Value)
+		}
+		writeBytes(payload.Value())
+	}
+
+	var id flow.Identifier
+	copy(id[:], h.Sum(nil))
+	return id, nil
+}
+
+func (d *executionDataDigest) key() string {
+	var b strings.Builder
+	b.WriteString(d.blockID.String())
+	b.WriteString(d.id.String())
+	for _, chunk := range d.chunks {
+		fmt.Fprintf(&b, "|%s:%s:%s", chunk.collectionID, chunk.eventsHash, chunk.trieUpdateHash)
+	}
+	return b.String()
+}
+
+// diff returns a description of each field in other that differs from d.
+func (d *executionDataDigest) diff(other *executionDataDigest) []string {
+	var diffs []string
+	if d.blockID != other.blockID {
+		diffs = append(diffs, fmt.Sprintf("block ID: expected %s, got %s", d.blockID, other.blockID))
+	}
+	if d.id != other.id {
+		diffs = append(diffs, fmt.Sprintf("execution data ID: expected %s, got %s", d.id, other.id))
+	}
+	if len(d.chunks) != len(other.chunks) {
+		diffs = append(diffs, fmt.Sprintf("chunk count: expected %d, got %d", len(d.chunks), len(other.chunks)))
+	}
+
+	for i := 0; i < len(d.chunks) && i < len(other.chunks); i++ {
+		expected, actual := d.chunks[i], other.chunks[i]
+		if expected.collectionID != actual.collectionID {
+			diffs = append(diffs, fmt.Sprintf("chunk %d collection: expected %s (%d transactions), got %s (%d transactions)",
+				i, expected.collectionID, expected.transactions, actual.collectionID, actual.transactions))
+		}
+		if expected.eventsHash != actual.eventsHash {
+			diffs = append(diffs, fmt.Sprintf("chunk %d events: expected %s (%d events), got %s (%d events)",
+				i, expected.eventsHash, expected.events, actual.eventsHash, actual.events))
+		}
+		if expected.trieUpdateHash != actual.trieUpdateHash {
+			diffs = append(diffs, fmt.Sprintf("chunk %d trie update: expected %s (%d payloads), got %s (%d payloads)",
+				i, expected.trieUpdateHash, expected.payloads, actual.trieUpdateHash, actual.payloads))
+		}
+	}
+
+	return diffs
+}
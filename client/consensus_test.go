@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestConsensusClient returns a consensus client for the nodes, with addresses a, b, c, etc.
+func newTestConsensusClient(quorum int, nodes ...*testAccessNode) *ConsensusClient {
+	c := &ConsensusClient{
+		config: ConsensusConfig{
+			Quorum:       quorum,
+			Timeout:      5 * time.Second,
+			PollInterval: time.Millisecond,
+		},
+		log:         NoopLogger{},
+		divergences: make(chan *Divergence, divergenceBufferSize),
+	}
+	for i, node := range nodes {
+		c.addresses = append(c.addresses, string(rune('a'+i)))
+		c.clients = append(c.clients, newTestClient(node))
+	}
+	return c
+}
+
+func TestConsensusGetExecutionDataByHeight(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	denied := status.Error(codes.PermissionDenied, "denied")
+
+	tests := []struct {
+		name   string
+		quorum int
+
+		// setup configures the nodes a, b and c
+		setup func(a, b, c *testAccessNode)
+
+		// diverged are the addresses of the nodes reported as diverging
+		diverged []string
+
+		// err is "" if data is returned, otherwise "not available" or "error"
+		err string
+	}{
+		{
+			name:   "all agree",
+			quorum: 3,
+			setup:  func(a, b, c *testAccessNode) {},
+		},
+		{
+			name:   "quorum despite divergence",
+			quorum: 2,
+			setup: func(a, b, c *testAccessNode) {
+				// nodes responding after the quorum is reached aren't compared, so c responds first
+				a.delay = 20 * time.Millisecond
+				b.delay = 20 * time.Millisecond
+				c.addEvents(5, "A.1.Token.Minted")
+			},
+			diverged: []string{"c"},
+		},
+		{
+			name:   "quorum of nodes with data",
+			quorum: 2,
+			setup:  func(a, b, c *testAccessNode) { c.setSealed(3) },
+		},
+		{
+			name:     "divergence prevents quorum",
+			quorum:   3,
+			setup:    func(a, b, c *testAccessNode) { c.addEvents(5, "A.1.Token.Minted") },
+			diverged: []string{"c"},
+			err:      "error",
+		},
+		{
+			name:   "not available on too many nodes",
+			quorum: 2,
+			setup: func(a, b, c *testAccessNode) {
+				b.setSealed(3)
+				c.setSealed(4)
+			},
+			err: "not available",
+		},
+		{
+			name:   "transient failures are not available",
+			quorum: 2,
+			setup: func(a, b, c *testAccessNode) {
+				b.fail("GetExecutionDataByBlockID", unavailable)
+				c.fail("GetLatestBlockHeader", unavailable)
+			},
+			err: "not available",
+		},
+		{
+			name:   "permanent failures prevent quorum",
+			quorum: 2,
+			setup: func(a, b, c *testAccessNode) {
+				b.fail("GetExecutionDataByBlockID", denied)
+				c.fail("GetExecutionDataByBlockID", denied)
+			},
+			err: "error",
+		},
+		{
+			name:   "transient failures too few to complete a quorum",
+			quorum: 3,
+			setup: func(a, b, c *testAccessNode) {
+				b.fail("GetExecutionDataByBlockID", denied)
+				c.fail("GetExecutionDataByBlockID", unavailable)
+			},
+			err: "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b, c := newTestAccessNode(10), newTestAccessNode(10), newTestAccessNode(10)
+			tt.setup(a, b, c)
+			client := newTestConsensusClient(tt.quorum, a, b, c)
+
+			execData, err := client.GetExecutionDataByHeight(context.Background(), 5)
+			switch tt.err {
+			case "":
+				require.NoError(t, err)
+				assert.Equal(t, testBlockID(5, 0), execData.BlockID)
+			case "not available":
+				assert.True(t, IsNotAvailable(err), "expected not available error, got %v", err)
+			default:
+				require.Error(t, err)
+				assert.False(t, IsNotAvailable(err), "expected permanent error, got %v", err)
+			}
+
+			var diverged []string
+			for len(client.divergences) > 0 {
+				divergence := <-client.divergences
+				assert.Equal(t, uint64(5), divergence.Height)
+				assert.NotEmpty(t, divergence.Differences)
+				diverged = append(diverged, divergence.Address)
+			}
+			assert.Equal(t, tt.diverged, diverged)
+		})
+	}
+}
+
+func TestConsensusSubscribe(t *testing.T) {
+	t.Run("waits out transient failures", func(t *testing.T) {
+		a, b, c := newTestAccessNode(10), newTestAccessNode(10), newTestAccessNode(10)
+		b.fail("GetBlockHeaderByHeight", status.Error(codes.Unavailable, "unavailable"))
+		c.fail("GetBlockHeaderByHeight", status.Error(codes.DeadlineExceeded, "timeout"))
+		client := newTestConsensusClient(2, a, b, c)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := client.Subscribe(ctx, 5)
+		require.NoError(t, err)
+
+		for _, height := range []uint64{5, 6} {
+			response := <-sub.Channel()
+			assert.Equal(t, height, response.Height)
+			assert.Equal(t, testBlockID(height, 0), response.BlockID)
+		}
+	})
+
+	t.Run("waits for new blocks", func(t *testing.T) {
+		a, b := newTestAccessNode(4), newTestAccessNode(4)
+		client := newTestConsensusClient(2, a, b)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := client.Subscribe(ctx, 5)
+		require.NoError(t, err)
+
+		time.Sleep(10 * time.Millisecond)
+		a.setSealed(5)
+		b.setSealed(5)
+
+		response := <-sub.Channel()
+		assert.Equal(t, uint64(5), response.Height)
+	})
+
+	t.Run("ends on divergence", func(t *testing.T) {
+		a, b := newTestAccessNode(10), newTestAccessNode(10)
+		b.addEvents(6, "A.1.Token.Minted")
+		client := newTestConsensusClient(2, a, b)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := client.Subscribe(ctx, 5)
+		require.NoError(t, err)
+
+		var heights []uint64
+		for response := range sub.Channel() {
+			heights = append(heights, response.Height)
+		}
+		assert.Equal(t, []uint64{5}, heights)
+		assert.Error(t, sub.Err())
+	})
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// execution data for large blocks is well beyond grpc's default 4MiB limit
+	defaultMaxReceiveMessageSize = 128 << 20
+	defaultCallTimeout           = 30 * time.Second
+	defaultUserAgent             = "execdata-client"
+)
+
+// Compressors supported by WithCompression.
+const (
+	CompressionNone = ""
+	CompressionGzip = gzip.Name
+	CompressionZstd = "zstd"
+)
+
+// defaultKeepalive pings the access node when a connection has been idle, so idle streams are
+// not cut by load balancers. Access nodes must permit pings at this interval.
+var defaultKeepalive = keepalive.ClientParameters{
+	Time:    30 * time.Second,
+	Timeout: 20 * time.Second,
+}
+
+// grpcDialOptions returns the dial options for the typed grpc settings in the config.
+func (c *config) grpcDialOptions() []grpc.DialOption {
+	callOpts := []grpc.CallOption{grpc.MaxCallRecvMsgSize(c.maxReceiveMessageSize)}
+	if c.compression != CompressionNone {
+		callOpts = append(callOpts, grpc.UseCompressor(c.compression))
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(callOpts...),
+		grpc.WithKeepaliveParams(c.keepalive),
+		grpc.WithUserAgent(c.userAgent),
+	}
+	// retries are outermost so that each attempt is rate limited and has its own timeout
+	if c.retry != nil {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(newRetrier(*c.retry, c.logger).unaryInterceptor()))
+	}
+	if c.rateLimit != nil && c.rateLimit.RequestsPerSecond > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(rateLimitUnaryInterceptor(*c.rateLimit)))
+	}
+	if c.callTimeout > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(timeoutUnaryInterceptor(c.callTimeout)))
+	}
+
+	return opts
+}
+
+// timeoutUnaryInterceptor applies the timeout to unary calls whose context has no deadline.
+// Streams are long-lived, so they are not given a timeout.
+func timeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func init() {
+	// don't replace a zstd compressor registered by another package
+	if encoding.GetCompressor(CompressionZstd) == nil {
+		encoding.RegisterCompressor(newZstdCompressor())
+	}
+}
+
+// zstdCompressor is a grpc compressor using zstd, reusing encoders and decoders between messages.
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+var _ encoding.Compressor = (*zstdCompressor)(nil)
+
+func newZstdCompressor() *zstdCompressor {
+	c := &zstdCompressor{}
+	c.encoders.New = func() interface{} {
+		// options are static, so this can't fail
+		encoder, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return encoder
+	}
+	c.decoders.New = func() interface{} {
+		decoder, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		return decoder
+	}
+	return c
+}
+
+func (c *zstdCompressor) Name() string {
+	return CompressionZstd
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	encoder := c.encoders.Get().(*zstd.Encoder)
+	encoder.Reset(w)
+	return &zstdWriter{Encoder: encoder, pool: &c.encoders}, nil
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	decoder := c.decoders.Get().(*zstd.Decoder)
+	if err := decoder.Reset(r); err != nil {
+		c.decoders.Put(decoder)
+		return nil, err
+	}
+
+	return &zstdReader{decoder: decoder, pool: &c.decoders}, nil
+}
+
+// zstdWriter returns its encoder to the pool when closed.
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	err := w.Encoder.Close()
+	w.pool.Put(w.Encoder)
+	return err
+}
+
+// zstdReader streams the decompressed message, returning its decoder to the pool once the message
+// has been read or decoding fails.
+type zstdReader struct {
+	decoder *zstd.Decoder
+	pool    *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.decoder == nil {
+		return 0, io.EOF
+	}
+
+	n, err := r.decoder.Read(p)
+	if err != nil {
+		r.pool.Put(r.decoder)
+		r.decoder = nil
+	}
+	return n, err
+}
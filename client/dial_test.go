@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZstdCompressor(t *testing.T) {
+	c := newZstdCompressor()
+
+	compress := func(data []byte) []byte {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return buf.Bytes()
+	}
+
+	messages := [][]byte{
+		bytes.Repeat([]byte("execution data"), 1<<16),
+		[]byte("small"),
+		{},
+	}
+
+	// decoders are reused between messages once each has been read
+	for i := 0; i < 2; i++ {
+		for _, message := range messages {
+			r, err := c.Decompress(bytes.NewReader(compress(message)))
+			require.NoError(t, err)
+
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, len(message), len(data))
+			assert.True(t, bytes.Equal(message, data))
+
+			// reads after the end of the message don't use the returned decoder
+			n, err := r.Read(make([]byte, 1))
+			assert.Equal(t, 0, n)
+			assert.Equal(t, io.EOF, err)
+		}
+	}
+
+	t.Run("corrupt message", func(t *testing.T) {
+		compressed := compress(messages[0])
+		compressed[len(compressed)/2] ^= 0xff
+
+		r, err := c.Decompress(bytes.NewReader(compressed))
+		if err == nil {
+			_, err = io.ReadAll(r)
+		}
+		assert.Error(t, err)
+
+		// the decoder is still usable for the next message
+		r, err = c.Decompress(bytes.NewReader(compress(messages[1])))
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, messages[1], data)
+	})
+}
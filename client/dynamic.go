@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/onflow/flow-go/model/flow"
+	"google.golang.org/grpc"
+)
+
+var errSubscriptionClosed = errors.New("subscription closed")
+
+// DynamicEventsSubscription is an events subscription whose filter can be changed without gaps
+// in the heights covered.
+type DynamicEventsSubscription struct {
+	*Subscription[EventsResponse]
+
+	chain   flow.Chain
+	updates chan EventFilter
+	done    chan struct{}
+}
+
+// UpdateFilter changes the filter of the subscription. Responses for heights after the last
+// delivered response use the new filter, including responses already received but not yet
+// delivered.
+func (s *DynamicEventsSubscription) UpdateFilter(ctx context.Context, filter EventFilter) error {
+	if err := filter.Validate(s.chain); err != nil {
+		return fmt.Errorf("invalid event filter: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+		return errSubscriptionClosed
+	case s.updates <- filter:
+		return nil
+	}
+}
+
+// SubscribeDynamicEvents subscribes to events starting at the given block ID or height, with a
+// filter that can be changed using UpdateFilter.
+//
+// When the filter is updated, the subscription resubscribes from the height after the last
+// delivered response, so every height is covered by either the previous or the new filter.
+func (c *ExecutionDataClient) SubscribeDynamicEvents(
+	ctx context.Context,
+	startBlockID flow.Identifier,
+	startHeight uint64,
+	filter EventFilter,
+	opts ...grpc.CallOption,
+) (*DynamicEventsSubscription, error) {
+	if startBlockID != flow.ZeroID && startHeight > 0 {
+		return nil, fmt.Errorf("cannot specify both start block ID and start height")
+	}
+
+	// resubscribing before anything is delivered must start from the same block, so pin the
+	// latest sealed height instead of relying on the access node's default.
+	if startBlockID == flow.ZeroID && startHeight == 0 {
+		latest, err := c.GetLatestSealedHeight(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("could not get latest sealed height: %w", err)
+		}
+		startHeight = latest
+	}
+
+	upstreamCtx, cancel := context.WithCancel(ctx)
+	upstream, err := c.SubscribeEvents(upstreamCtx, startBlockID, startHeight, filter, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s := &DynamicEventsSubscription{
+		Subscription: newStreamSubscription[EventsResponse](streamEvents, c.metrics),
+		chain:        c.chain,
+		updates:      make(chan EventFilter),
+		done:         make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		defer close(s.ch)
+
+		// next is the height after the last delivered response, or 0 if none were delivered
+		var next uint64
+
+		// cancel stops the current upstream subscription, and discards its remaining responses
+		// so its goroutine can exit.
+		stop := func() {
+			cancel()
+			for range upstream.Channel() {
+			}
+		}
+
+		for {
+			var response EventsResponse
+			var ok bool
+
+			select {
+			case <-ctx.Done():
+				stop()
+				s.err = ctx.Err()
+				return
+
+			case filter = <-s.updates:
+				// fall through to resubscribe below
+
+			case response, ok = <-upstream.Channel():
+				if !ok {
+					cancel()
+					s.err = upstream.Err()
+					return
+				}
+
+				select {
+				case <-ctx.Done():
+					stop()
+					s.err = ctx.Err()
+					return
+				case filter = <-s.updates:
+					// the response was selected by the previous filter, so drop it and
+					// resubscribe from its height with the new filter
+				case s.ch <- response:
+					next = response.Height + 1
+					continue
+				}
+			}
+
+			stop()
+
+			resumeBlockID, resumeHeight := startBlockID, startHeight
+			if next > 0 {
+				resumeBlockID, resumeHeight = flow.ZeroID, next
+			}
+
+			c.log.Debug("updating events subscription filter",
+				F("subscription_id", s.ID()),
+				F("start_block_id", resumeBlockID),
+				F("start_height", resumeHeight),
+			)
+
+			upstreamCtx, cancel = context.WithCancel(ctx)
+			upstream, err = c.SubscribeEvents(upstreamCtx, resumeBlockID, resumeHeight, filter, opts...)
+			if err != nil {
+				cancel()
+				s.err = fmt.Errorf("could not resubscribe with updated filter: %w", err)
+				return
+			}
+		}
+	}()
+
+	return s, nil
+}
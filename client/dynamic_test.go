@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicEventsSubscription(t *testing.T) {
+	const (
+		created = "flow.AccountCreated"
+		updated = "flow.AccountContractUpdated"
+	)
+
+	t.Run("update resumes after the last delivered height", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		for height := uint64(10); height <= 20; height++ {
+			node.addEvents(height, created, updated)
+		}
+		c := newTestClient(node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := c.SubscribeDynamicEvents(ctx, flow.ZeroID, 10, EventFilter{EventTypes: []string{created}})
+		require.NoError(t, err)
+
+		var heights []uint64
+		receive := func(eventType string) {
+			response := <-sub.Channel()
+			heights = append(heights, response.Height)
+			require.Len(t, response.Events, 1, "height %d", response.Height)
+			assert.Equal(t, flow.EventType(eventType), response.Events[0].Type, "height %d", response.Height)
+		}
+
+		for i := 0; i < 3; i++ {
+			receive(created)
+		}
+
+		require.NoError(t, sub.UpdateFilter(ctx, EventFilter{EventTypes: []string{updated}}))
+
+		for i := 0; i < 3; i++ {
+			receive(updated)
+		}
+
+		// every height is delivered once, and the resubscription starts after the last delivered
+		assert.Equal(t, []uint64{10, 11, 12, 13, 14, 15}, heights)
+		assert.Equal(t, []uint64{10, 13}, node.subscribed())
+	})
+
+	t.Run("update before any delivery resumes from the start", func(t *testing.T) {
+		node := newTestAccessNode(100)
+		c := newTestClient(node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// the default start is pinned to the latest sealed height
+		sub, err := c.SubscribeDynamicEvents(ctx, flow.ZeroID, 0, EventFilter{EventTypes: []string{created}})
+		require.NoError(t, err)
+		require.NoError(t, sub.UpdateFilter(ctx, EventFilter{EventTypes: []string{updated}}))
+
+		response := <-sub.Channel()
+		assert.Equal(t, uint64(100), response.Height)
+		assert.Equal(t, []uint64{100, 100}, node.subscribed())
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		c := newTestClient(newTestAccessNode(100))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sub, err := c.SubscribeDynamicEvents(ctx, flow.ZeroID, 10, EventFilter{})
+		require.NoError(t, err)
+
+		assert.Error(t, sub.UpdateFilter(ctx, EventFilter{EventTypes: []string{"Token"}}))
+	})
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// NotAvailableError is returned when the requested block is not yet sealed, or its execution
+// data has not been indexed by the access node yet. Callers will typically retry later.
+type NotAvailableError struct {
+	Height  uint64
+	BlockID flow.Identifier
+
+	// LatestSealedHeight is the latest sealed height known to the client, or 0 if unknown.
+	LatestSealedHeight uint64
+
+	Err error
+}
+
+func (e *NotAvailableError) Error() string {
+	switch {
+	case e.BlockID == flow.ZeroID:
+		return fmt.Sprintf("data for height %d is not available yet (latest sealed height: %d)", e.Height, e.LatestSealedHeight)
+	case e.Height == 0:
+		return fmt.Sprintf("execution data for block %s is not available yet", e.BlockID)
+	default:
+		return fmt.Sprintf("execution data for block %d %s is not available yet", e.Height, e.BlockID)
+	}
+}
+
+func (e *NotAvailableError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotAvailable returns true if the error indicates the requested data is not available yet.
+func IsNotAvailable(err error) bool {
+	var target *NotAvailableError
+	return errors.As(err, &target)
+}
+
+// VerificationError is returned when data received from the access node does not match the
+// commitments in the block's sealed execution result.
+type VerificationError struct {
+	BlockID  flow.Identifier
+	Expected flow.Identifier
+	Computed flow.Identifier
+
+	// Field describes the data that failed verification.
+	Field string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%s for block %s does not match sealed execution result: expected %s, computed %s",
+		e.Field, e.BlockID, e.Expected, e.Computed)
+}
+
+// IsVerificationError returns true if the error indicates data failed verification.
+func IsVerificationError(err error) bool {
+	var target *VerificationError
+	return errors.As(err, &target)
+}
+
+// ErrGap is returned by a subscription when one or more heights were skipped by the stream.
+type ErrGap struct {
+	// From and To are the first and last (inclusive) missing heights.
+	From uint64
+	To   uint64
+
+	// Err is the error encountered while repairing the gap, if any.
+	Err error
+}
+
+func (e *ErrGap) Error() string {
+	msg := fmt.Sprintf("subscription skipped heights %d to %d", e.From, e.To)
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: could not repair gap: %v", msg, e.Err)
+	}
+	return msg
+}
+
+func (e *ErrGap) Unwrap() error {
+	return e.Err
+}
+
+// ErrOutOfOrder is returned by a subscription when a height is received at or below the
+// previously delivered height, and it is not a duplicate of the previous block.
+type ErrOutOfOrder struct {
+	Previous uint64
+	Height   uint64
+}
+
+func (e *ErrOutOfOrder) Error() string {
+	return fmt.Sprintf("subscription received height %d after height %d", e.Height, e.Previous)
+}
+
+// ErrParentMismatch is returned by a subscription when a block's parent is not the previously
+// delivered block.
+type ErrParentMismatch struct {
+	Height   uint64
+	BlockID  flow.Identifier
+	Expected flow.Identifier
+	Actual   flow.Identifier
+}
+
+func (e *ErrParentMismatch) Error() string {
+	return fmt.Sprintf("block %d %s has parent %s, expected %s", e.Height, e.BlockID, e.Actual, e.Expected)
+}
+
+// ErrStalled is returned by a subscription when no message was received within the configured
+// stall timeout, and reconnecting is disabled.
+type ErrStalled struct {
+	Timeout time.Duration
+}
+
+func (e *ErrStalled) Error() string {
+	return fmt.Sprintf("subscription stalled: no message received for %s", e.Timeout)
+}
+
+// ErrSlowConsumer is returned by a broker subscription when its buffer filled up because the
+// consumer was not keeping up with the stream.
+type ErrSlowConsumer struct {
+	Height     uint64
+	BufferSize int
+}
+
+func (e *ErrSlowConsumer) Error() string {
+	return fmt.Sprintf("subscriber buffer of %d responses full at height %d", e.BufferSize, e.Height)
+}
@@ -0,0 +1,721 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"google.golang.org/grpc"
+)
+
+// EventExpression is a client-side event predicate, parsed from a small expression language:
+//
+//	type == "A.*.FlowToken.TokensDeposited" and amount > 1000
+//	to in {0x01, 0xf8d6e0586b0a20c7} or not (type == "flow.*")
+//
+// `type` matches the event type against a glob pattern, where * matches any characters. Any
+// other identifier refers to a field of the event's payload, with nested struct fields joined
+// by dots (e.g. `vault.balance`). Field names can be quoted in backticks to refer to payload
+// fields named like keywords, e.g. `type` for a field called type. Fields can be compared to
+// numbers, "strings", addresses, true, false and nil using ==, !=, <, <=, >, >= and in {…}.
+// Comparisons on fields that are missing, or of a different type, are false. Predicates are
+// combined with and, or, not and parentheses, or equivalently &&, || and !.
+//
+// Payload fields are decoded from JSON-CDC, so field predicates can't be evaluated on CCF
+// encoded events, which are requested when verification is enabled. SubscribeEventsWhere rejects
+// such expressions when the subscription is created.
+type EventExpression struct {
+	source string
+	root   exprNode
+
+	// fields is true if the expression refers to payload fields
+	fields bool
+}
+
+// ParseEventExpression parses an event expression.
+func ParseEventExpression(source string) (*EventExpression, error) {
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+	}
+
+	return &EventExpression{source: source, root: root, fields: p.fields}, nil
+}
+
+func (e *EventExpression) String() string {
+	return e.source
+}
+
+// UsesPayloadFields returns true if the expression refers to fields of the event payloads,
+// which requires them to be JSON-CDC encoded.
+func (e *EventExpression) UsesPayloadFields() bool {
+	return e.fields
+}
+
+// Match returns true if the event is selected by the expression.
+func (e *EventExpression) Match(event flow.Event) (bool, error) {
+	ev := &exprEvent{event: event}
+	if e.fields {
+		fields, err := decodeEventFields(event.Payload)
+		if err != nil {
+			return false, fmt.Errorf("could not decode payload of %s event: %w", event.Type, err)
+		}
+		ev.fields = fields
+	}
+
+	return e.root.eval(ev), nil
+}
+
+// SubscribeEventsWhere subscribes to events as SubscribeEvents does, delivering only the events
+// matching the expression. The filter is applied by the access node, and the expression to
+// the events it returns.
+//
+// Events whose payload can't be decoded are skipped and logged. An error is returned if the
+// expression refers to payload fields and verification is enabled, since verified events are
+// CCF encoded.
+func (c *ExecutionDataClient) SubscribeEventsWhere(
+	ctx context.Context,
+	startBlockID flow.Identifier,
+	startHeight uint64,
+	filter EventFilter,
+	expr *EventExpression,
+	opts ...grpc.CallOption,
+) (*Subscription[EventsResponse], error) {
+	if expr.UsesPayloadFields() && c.eventEncoding() != entities.EventEncodingVersion_JSON_CDC_V0 {
+		return nil, fmt.Errorf("expression %q refers to payload fields, which can't be decoded from the CCF events used for verification", expr)
+	}
+
+	sub, err := c.SubscribeEvents(ctx, startBlockID, startHeight, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterEventsByExpression(ctx, sub, expr, c.log, c.metrics), nil
+}
+
+// FilterEventsByExpression returns a subscription that delivers the responses from sub with only
+// the events matching the expression. It's intended to be applied after the access node's
+// filter. Responses left with no events are dropped, but heartbeats are passed through.
+// Events whose payload can't be decoded are skipped.
+func FilterEventsByExpression(
+	ctx context.Context,
+	sub *Subscription[EventsResponse],
+	expr *EventExpression,
+) *Subscription[EventsResponse] {
+	return filterEventsByExpression(ctx, sub, expr, NoopLogger{}, NoopMetrics{})
+}
+
+// filterEventsByExpression is FilterEventsByExpression, logging skipped events and reporting
+// them as decode_event errors.
+func filterEventsByExpression(
+	ctx context.Context,
+	sub *Subscription[EventsResponse],
+	expr *EventExpression,
+	log Logger,
+	metrics Metrics,
+) *Subscription[EventsResponse] {
+	filtered := NewSubscription[EventsResponse]()
+
+	go func() {
+		defer close(filtered.ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				filtered.err = ctx.Err()
+				return
+			case response, ok := <-sub.Channel():
+				if !ok {
+					filtered.err = sub.Err()
+					return
+				}
+
+				heartbeat := response.IsHeartbeat()
+
+				var events []flow.Event
+				for _, event := range response.Events {
+					match, err := expr.Match(event)
+					if err != nil {
+						log.Warn("skipping event with undecodable payload",
+							F("height", response.Height),
+							F("block_id", response.BlockID),
+							F("event_type", event.Type),
+							F("transaction_id", event.TransactionID),
+							F("error", err),
+						)
+						metrics.Error("decode_event", err)
+						continue
+					}
+					if match {
+						events = append(events, event)
+					}
+				}
+				if len(events) == 0 && !heartbeat {
+					continue
+				}
+				response.Events = events
+
+				select {
+				case <-ctx.Done():
+					filtered.err = ctx.Err()
+					return
+				case filtered.ch <- response:
+				}
+			}
+		}
+	}()
+
+	return filtered
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenString
+	tokenNumber
+	tokenAddress
+	tokenOperator
+	tokenPunct
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lexExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"':
+			end := i + 1
+			for end < len(source) && source[end] != '"' {
+				if source[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(source) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text, err := strconv.Unquote(source[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: text, pos: i})
+			i = end + 1
+
+		case c == '`':
+			end := strings.IndexByte(source[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated field name at offset %d", i)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty field name at offset %d", i)
+			}
+			tokens = append(tokens, exprToken{kind: tokenQuotedIdent, text: source[i+1 : i+1+end], pos: i})
+			i += end + 2
+
+		case strings.HasPrefix(source[i:], "0x"):
+			end := i + 2
+			for end < len(source) && isHexDigit(source[end]) {
+				end++
+			}
+			if end == i+2 {
+				return nil, fmt.Errorf("invalid address at offset %d", i)
+			}
+			tokens = append(tokens, exprToken{kind: tokenAddress, text: source[i:end], pos: i})
+			i = end
+
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(source) && unicode.IsDigit(rune(source[i+1]))):
+			end := i + 1
+			for end < len(source) && (unicode.IsDigit(rune(source[end])) || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: source[i:end], pos: i})
+			i = end
+
+		case unicode.IsLetter(c) || c == '_':
+			end := i + 1
+			for end < len(source) && (unicode.IsLetter(rune(source[end])) || unicode.IsDigit(rune(source[end])) ||
+				source[end] == '_' || source[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: source[i:end], pos: i})
+			i = end
+
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!"} {
+				if strings.HasPrefix(source[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, exprToken{kind: tokenOperator, text: op, pos: i})
+				i += len(op)
+				continue
+			}
+			if strings.ContainsRune("(){},", c) {
+				tokens = append(tokens, exprToken{kind: tokenPunct, text: string(c), pos: i})
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+
+	return append(tokens, exprToken{kind: tokenEOF, text: "end of expression", pos: len(source)}), nil
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	fields bool
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it's one of the given operators or keywords.
+func (p *exprParser) accept(texts ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokenOperator && tok.kind != tokenIdent && tok.kind != tokenPunct {
+		return false
+	}
+	for _, text := range texts {
+		if tok.text == text {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *exprParser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at offset %d, found %q", text, tok.pos, tok.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or", "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and", "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept("not", "!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	field := p.next()
+	quoted := field.kind == tokenQuotedIdent
+	if !quoted && (field.kind != tokenIdent || isExprKeyword(field.text)) {
+		return nil, fmt.Errorf("expected field name at offset %d, found %q", field.pos, field.text)
+	}
+
+	op := p.next()
+	var values []exprValue
+	switch {
+	case op.kind == tokenOperator && op.text != "&&" && op.text != "||" && op.text != "!":
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = []exprValue{value}
+
+	case op.kind == tokenIdent && op.text == "in":
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		for {
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect("}"); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("expected operator at offset %d, found %q", op.pos, op.text)
+	}
+
+	if field.text == "type" && !quoted {
+		if op.text != "==" && op.text != "!=" && op.text != "in" {
+			return nil, fmt.Errorf("type only supports ==, != and in, at offset %d", op.pos)
+		}
+		patterns := make([]string, len(values))
+		for i, value := range values {
+			if value.kind != valueString {
+				return nil, fmt.Errorf("type must be compared to string patterns, at offset %d", op.pos)
+			}
+			if _, err := path.Match(value.str, ""); err != nil {
+				return nil, fmt.Errorf("invalid type pattern %q: %w", value.str, err)
+			}
+			patterns[i] = value.str
+		}
+		return typeNode{negate: op.text == "!=", patterns: patterns}, nil
+	}
+
+	p.fields = true
+	return compareNode{field: field.text, op: op.text, values: values}, nil
+}
+
+func (p *exprParser) parseValue() (exprValue, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return exprValue{kind: valueString, str: tok.text}, nil
+	case tokenAddress:
+		return exprValue{kind: valueAddress, str: normalizeAddress(tok.text)}, nil
+	case tokenNumber:
+		num, ok := new(big.Rat).SetString(tok.text)
+		if !ok {
+			return exprValue{}, fmt.Errorf("invalid number %q at offset %d", tok.text, tok.pos)
+		}
+		return exprValue{kind: valueNumber, num: num}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true", "false":
+			return exprValue{kind: valueBool, b: tok.text == "true"}, nil
+		case "nil":
+			return exprValue{kind: valueNil}, nil
+		}
+	}
+	return exprValue{}, fmt.Errorf("expected value at offset %d, found %q", tok.pos, tok.text)
+}
+
+func isExprKeyword(text string) bool {
+	switch text {
+	case "and", "or", "not", "in", "true", "false", "nil":
+		return true
+	}
+	return false
+}
+
+type valueKind int
+
+const (
+	valueNil valueKind = iota
+	valueString
+	valueNumber
+	valueBool
+	valueAddress
+)
+
+type exprValue struct {
+	kind valueKind
+	str  string
+	num  *big.Rat
+	b    bool
+}
+
+// compare returns the ordering of v and other, and false if they're not comparable.
+func (v exprValue) compare(other exprValue) (int, bool) {
+	if v.kind != other.kind {
+		return 0, false
+	}
+	switch v.kind {
+	case valueNumber:
+		return v.num.Cmp(other.num), true
+	case valueString, valueAddress:
+		return strings.Compare(v.str, other.str), true
+	case valueBool:
+		if v.b == other.b {
+			return 0, true
+		}
+	case valueNil:
+		return 0, true
+	}
+	return 0, false
+}
+
+func normalizeAddress(address string) string {
+	hex := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	if len(hex) < 2*flow.AddressLength {
+		hex = strings.Repeat("0", 2*flow.AddressLength-len(hex)) + hex
+	}
+	return "0x" + hex
+}
+
+// exprEvent is the event an expression is evaluated against.
+type exprEvent struct {
+	event  flow.Event
+	fields map[string]exprValue
+}
+
+type exprNode interface {
+	eval(ev *exprEvent) bool
+}
+
+type andNode struct{ left, right exprNode }
+
+func (n andNode) eval(ev *exprEvent) bool { return n.left.eval(ev) && n.right.eval(ev) }
+
+type orNode struct{ left, right exprNode }
+
+func (n orNode) eval(ev *exprEvent) bool { return n.left.eval(ev) || n.right.eval(ev) }
+
+type notNode struct{ operand exprNode }
+
+func (n notNode) eval(ev *exprEvent) bool { return !n.operand.eval(ev) }
+
+type typeNode struct {
+	negate   bool
+	patterns []string
+}
+
+func (n typeNode) eval(ev *exprEvent) bool {
+	for _, pattern := range n.patterns {
+		// patterns were validated when parsed
+		if match, _ := path.Match(pattern, string(ev.event.Type)); match {
+			return !n.negate
+		}
+	}
+	return n.negate
+}
+
+type compareNode struct {
+	field  string
+	op     string
+	values []exprValue
+}
+
+func (n compareNode) eval(ev *exprEvent) bool {
+	value, ok := ev.fields[n.field]
+	if !ok {
+		return false
+	}
+
+	if n.op == "in" {
+		for _, candidate := range n.values {
+			if cmp, ok := value.compare(candidate); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	cmp, ok := value.compare(n.values[0])
+	if !ok {
+		return false
+	}
+
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	}
+
+	// only numbers and strings are ordered
+	if value.kind != valueNumber && value.kind != valueString {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// jsonCDCValue is a JSON-CDC encoded cadence value.
+type jsonCDCValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type jsonCDCComposite struct {
+	ID     string `json:"id"`
+	Fields []struct {
+		Name  string       `json:"name"`
+		Value jsonCDCValue `json:"value"`
+	} `json:"fields"`
+}
+
+// decodeEventFields decodes a JSON-CDC event payload into its fields, with nested composite
+// fields flattened into dotted names. Arrays, dictionaries and other values that can't be
+// compared are omitted.
+func decodeEventFields(payload []byte) (map[string]exprValue, error) {
+	var event jsonCDCValue
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("payload is not JSON-CDC: %w", err)
+	}
+	if event.Type != "Event" {
+		return nil, fmt.Errorf("payload has type %q, expected Event", event.Type)
+	}
+
+	fields := make(map[string]exprValue)
+	if err := flattenCDCComposite(event.Value, "", fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func flattenCDCComposite(raw json.RawMessage, prefix string, fields map[string]exprValue) error {
+	var composite jsonCDCComposite
+	if err := json.Unmarshal(raw, &composite); err != nil {
+		return err
+	}
+
+	for _, field := range composite.Fields {
+		if err := flattenCDCValue(field.Value, prefix+field.Name, fields); err != nil {
+			return fmt.Errorf("invalid field %s: %w", prefix+field.Name, err)
+		}
+	}
+	return nil
+}
+
+func flattenCDCValue(v jsonCDCValue, name string, fields map[string]exprValue) error {
+	switch v.Type {
+	case "Optional":
+		if len(v.Value) == 0 || string(v.Value) == "null" {
+			fields[name] = exprValue{kind: valueNil}
+			return nil
+		}
+		var inner jsonCDCValue
+		if err := json.Unmarshal(v.Value, &inner); err != nil {
+			return err
+		}
+		return flattenCDCValue(inner, name, fields)
+
+	case "Void":
+		fields[name] = exprValue{kind: valueNil}
+
+	case "Bool":
+		var b bool
+		if err := json.Unmarshal(v.Value, &b); err != nil {
+			return err
+		}
+		fields[name] = exprValue{kind: valueBool, b: b}
+
+	case "String", "Character":
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return err
+		}
+		fields[name] = exprValue{kind: valueString, str: s}
+
+	case "Address":
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return err
+		}
+		fields[name] = exprValue{kind: valueAddress, str: normalizeAddress(s)}
+
+	case "Int", "Int8", "Int16", "Int32", "Int64", "Int128", "Int256",
+		"UInt", "UInt8", "UInt16", "UInt32", "UInt64", "UInt128", "UInt256",
+		"Word8", "Word16", "Word32", "Word64", "Fix64", "UFix64":
+		var s string
+		if err := json.Unmarshal(v.Value, &s); err != nil {
+			return err
+		}
+		num, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return fmt.Errorf("invalid %s value %q", v.Type, s)
+		}
+		fields[name] = exprValue{kind: valueNumber, num: num}
+
+	case "Struct", "Resource", "Event", "Contract", "Enum":
+		return flattenCDCComposite(v.Value, name+".", fields)
+	}
+
+	return nil
+}
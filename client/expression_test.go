@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLexExpression(t *testing.T) {
+	type token struct {
+		kind tokenKind
+		text string
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected []token
+		err      bool
+	}{
+		{
+			name:   "comparison",
+			source: `amount >= 10.5`,
+			expected: []token{
+				{tokenIdent, "amount"}, {tokenOperator, ">="}, {tokenNumber, "10.5"},
+			},
+		},
+		{
+			name:   "negative number",
+			source: `x>-3`,
+			expected: []token{
+				{tokenIdent, "x"}, {tokenOperator, ">"}, {tokenNumber, "-3"},
+			},
+		},
+		{
+			name:   "string with escapes",
+			source: `type == "A.*.\"Q\""`,
+			expected: []token{
+				{tokenIdent, "type"}, {tokenOperator, "=="}, {tokenString, `A.*."Q"`},
+			},
+		},
+		{
+			name:   "set of addresses",
+			source: `to in {0x01, 0xF8d6}`,
+			expected: []token{
+				{tokenIdent, "to"}, {tokenIdent, "in"}, {tokenPunct, "{"}, {tokenAddress, "0x01"},
+				{tokenPunct, ","}, {tokenAddress, "0xF8d6"}, {tokenPunct, "}"},
+			},
+		},
+		{
+			name:   "nested field and boolean operators",
+			source: `!(vault.balance<1)&&a||b`,
+			expected: []token{
+				{tokenOperator, "!"}, {tokenPunct, "("}, {tokenIdent, "vault.balance"}, {tokenOperator, "<"},
+				{tokenNumber, "1"}, {tokenPunct, ")"}, {tokenOperator, "&&"}, {tokenIdent, "a"},
+				{tokenOperator, "||"}, {tokenIdent, "b"},
+			},
+		},
+		{
+			name:   "quoted field",
+			source: "`type` != `in`",
+			expected: []token{
+				{tokenQuotedIdent, "type"}, {tokenOperator, "!="}, {tokenQuotedIdent, "in"},
+			},
+		},
+		{name: "empty", source: "  ", expected: nil},
+		{name: "unterminated string", source: `type == "A.*`, err: true},
+		{name: "unterminated field", source: "`type == 1", err: true},
+		{name: "empty field", source: "`` == 1", err: true},
+		{name: "address without digits", source: `to == 0x`, err: true},
+		{name: "unexpected character", source: `amount = 1`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := lexExpression(tt.source)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			// the last token is always the end of the expression
+			require.NotEmpty(t, tokens)
+			assert.Equal(t, tokenEOF, tokens[len(tokens)-1].kind)
+
+			var actual []token
+			for _, tok := range tokens[:len(tokens)-1] {
+				actual = append(actual, token{tok.kind, tok.text})
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestParseEventExpression(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		fields bool
+		err    bool
+	}{
+		{name: "type", source: `type == "A.*.FlowToken.*"`},
+		{name: "type set", source: `type in {"flow.*", "A.*.FlowToken.TokensDeposited"}`},
+		{name: "negated type", source: `not (type != "flow.*")`},
+		{name: "field", source: `amount > 1000`, fields: true},
+		{name: "quoted type field", source: "`type` == 1", fields: true},
+		{name: "quoted keyword field", source: "`in` == true", fields: true},
+		{name: "precedence", source: `type == "a" or amount > 1 and not to == nil`, fields: true},
+		{name: "symbolic operators", source: `!(a == 1) && b != 2 || c <= 3`, fields: true},
+
+		{name: "empty", source: ``, err: true},
+		{name: "missing value", source: `amount >`, err: true},
+		{name: "missing operator", source: `amount 1`, err: true},
+		{name: "keyword as field", source: `in == 1`, err: true},
+		{name: "value as field", source: `1 == amount`, err: true},
+		{name: "trailing tokens", source: `amount > 1 amount`, err: true},
+		{name: "unclosed paren", source: `(amount > 1`, err: true},
+		{name: "unclosed set", source: `to in {0x01`, err: true},
+		{name: "empty set", source: `to in {}`, err: true},
+		{name: "type ordered", source: `type > "a"`, err: true},
+		{name: "type not string", source: `type == 1`, err: true},
+		{name: "invalid type pattern", source: `type == "A.["`, err: true},
+		{name: "invalid number", source: `amount > 1.2.3`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseEventExpression(tt.source)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.fields, expr.UsesPayloadFields())
+			assert.Equal(t, tt.source, expr.String())
+		})
+	}
+}
+
+func TestEventExpressionMatch(t *testing.T) {
+	event := flow.Event{
+		Type: "A.1654653399040a61.FlowToken.TokensDeposited",
+		Payload: []byte(`{"type":"Event","value":{"id":"A.1654653399040a61.FlowToken.TokensDeposited","fields":[
+			{"name":"amount","value":{"type":"UFix64","value":"1500.00000000"}},
+			{"name":"to","value":{"type":"Optional","value":{"type":"Address","value":"0xf8d6e0586b0a20c7"}}},
+			{"name":"from","value":{"type":"Optional","value":null}},
+			{"name":"type","value":{"type":"String","value":"deposit"}},
+			{"name":"vault","value":{"type":"Struct","value":{"id":"S.Vault","fields":[
+				{"name":"balance","value":{"type":"Int","value":"7"}},
+				{"name":"locked","value":{"type":"Bool","value":false}}
+			]}}}
+		]}}`),
+	}
+
+	tests := []struct {
+		source   string
+		expected bool
+	}{
+		{`type == "A.*.FlowToken.TokensDeposited"`, true},
+		{`type == "A.*.FlowToken.TokensWithdrawn"`, false},
+		{`type != "flow.*"`, true},
+		{`type in {"flow.*", "A.*.FlowToken.*"}`, true},
+		{`amount > 1000`, true},
+		{`amount == 1500`, true},
+		{`amount < 1500.00000001`, true},
+		{`amount >= 1500.1`, false},
+		{`to == 0xf8d6e0586b0a20c7`, true},
+		{`to in {0x01, 0xF8D6E0586B0A20C7}`, true},
+		{`to == "0xf8d6e0586b0a20c7"`, false},
+		{`from == nil`, true},
+		{`from != nil`, false},
+		{"`type` == \"deposit\"", true},
+		{`vault.balance == 7`, true},
+		{`vault.locked == false`, true},
+		{`vault.locked > false`, false},
+		{`missing == 1`, false},
+		{`missing != 1`, false},
+		{`not missing == 1`, true},
+		{`amount > 1000 and vault.balance < 5`, false},
+		{`amount > 2000 or vault.balance < 10`, true},
+		{`!(amount > 2000) && type == "A.*"`, true},
+		{`!(amount > 2000) && type == "flow.*"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := ParseEventExpression(tt.source)
+			require.NoError(t, err)
+
+			match, err := expr.Match(event)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, match)
+		})
+	}
+
+	t.Run("payload not JSON-CDC", func(t *testing.T) {
+		expr, err := ParseEventExpression(`amount > 1`)
+		require.NoError(t, err)
+
+		_, err = expr.Match(flow.Event{Type: event.Type, Payload: []byte{0xd8, 0x82}})
+		assert.Error(t, err)
+
+		// type predicates don't decode the payload
+		expr, err = ParseEventExpression(`type == "A.*"`)
+		require.NoError(t, err)
+		_, err = expr.Match(flow.Event{Type: event.Type, Payload: []byte{0xd8, 0x82}})
+		assert.NoError(t, err)
+	})
+}
+
+func TestSubscribeEventsWhereVerification(t *testing.T) {
+	// field predicates can't be evaluated on the CCF events requested for verification
+	c := &ExecutionDataClient{verifyEvents: true}
+
+	expr, err := ParseEventExpression(`amount > 1000`)
+	require.NoError(t, err)
+
+	_, err = c.SubscribeEventsWhere(context.Background(), flow.ZeroID, 0, EventFilter{}, expr)
+	assert.Error(t, err)
+}
+
+func TestFilterEventsByExpression(t *testing.T) {
+	deposit := flow.Event{
+		Type: "A.1654653399040a61.FlowToken.TokensDeposited",
+		Payload: []byte(`{"type":"Event","value":{"id":"A.1654653399040a61.FlowToken.TokensDeposited","fields":[
+			{"name":"amount","value":{"type":"UFix64","value":"1500.00000000"}}
+		]}}`),
+	}
+	undecodable := flow.Event{Type: deposit.Type, Payload: []byte{0xd8, 0x82}}
+
+	expr, err := ParseEventExpression(`amount > 1000`)
+	require.NoError(t, err)
+
+	metrics, err := NewPrometheusMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	upstream := NewSubscription[EventsResponse]()
+	go func() {
+		defer close(upstream.ch)
+		upstream.ch <- EventsResponse{Height: 1, Events: []flow.Event{undecodable, deposit}}
+		upstream.ch <- EventsResponse{Height: 2, Events: []flow.Event{undecodable}}
+		upstream.ch <- EventsResponse{Height: 3, Events: []flow.Event{deposit}}
+	}()
+
+	// undecodable events are skipped without ending the subscription
+	filtered := filterEventsByExpression(ctx, upstream, expr, NoopLogger{}, metrics)
+
+	var heights []uint64
+	for response := range filtered.Channel() {
+		heights = append(heights, response.Height)
+		assert.Equal(t, []flow.Event{deposit}, response.Events, "height %d", response.Height)
+	}
+	require.NoError(t, filtered.Err())
+	assert.Equal(t, []uint64{1, 3}, heights)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.errors.WithLabelValues("decode_event", "Unknown")))
+}
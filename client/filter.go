@@ -0,0 +1,178 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/onflow/flow-go/model/flow"
+	"go.uber.org/multierr"
+)
+
+var cadenceIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks that the filter's event types, contracts and addresses are well formed, and
+// that addresses are valid for the chain. Event types must be of the form
+// A.<address>.<Contract>.<Event> or flow.<Event>, and contracts of the form A.<address>.<Contract>.
+// If chain is nil, addresses are only checked for format.
+//
+// Addresses are normalized to lower case hex without a 0x prefix, and duplicate entries are
+// removed. All problems found are returned together.
+func (f *EventFilter) Validate(chain flow.Chain) error {
+	var errs error
+
+	eventTypes := make([]string, 0, len(f.EventTypes))
+	for _, eventType := range f.EventTypes {
+		normalized, err := validateEventType(eventType, chain)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid event type %q: %w", eventType, err))
+			continue
+		}
+		eventTypes = append(eventTypes, normalized)
+	}
+
+	contracts := make([]string, 0, len(f.Contracts))
+	for _, contract := range f.Contracts {
+		normalized, err := validateContract(contract, chain)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid contract %q: %w", contract, err))
+			continue
+		}
+		contracts = append(contracts, normalized)
+	}
+
+	addresses := make([]string, 0, len(f.Addresses))
+	for _, address := range f.Addresses {
+		normalized, err := validateAddress(address, chain)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("invalid address %q: %w", address, err))
+			continue
+		}
+		addresses = append(addresses, normalized)
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	f.EventTypes = dedupe(eventTypes)
+	f.Contracts = dedupe(contracts)
+	f.Addresses = dedupe(addresses)
+
+	return nil
+}
+
+func validateEventType(eventType string, chain flow.Chain) (string, error) {
+	parts := strings.Split(strings.TrimSpace(eventType), ".")
+
+	if parts[0] == "flow" {
+		if len(parts) != 2 || !cadenceIdentifier.MatchString(parts[1]) {
+			return "", fmt.Errorf("expected flow.<Event>")
+		}
+		return strings.Join(parts, "."), nil
+	}
+
+	if len(parts) != 4 || parts[0] != "A" {
+		return "", fmt.Errorf("expected A.<address>.<Contract>.<Event> or flow.<Event>")
+	}
+
+	contract, err := validateContract(strings.Join(parts[:3], "."), chain)
+	if err != nil {
+		return "", err
+	}
+	if !cadenceIdentifier.MatchString(parts[3]) {
+		return "", fmt.Errorf("invalid event name %q", parts[3])
+	}
+
+	return contract + "." + parts[3], nil
+}
+
+func validateContract(contract string, chain flow.Chain) (string, error) {
+	parts := strings.Split(strings.TrimSpace(contract), ".")
+	if len(parts) != 3 || parts[0] != "A" {
+		return "", fmt.Errorf("expected A.<address>.<Contract>")
+	}
+
+	address, err := validateAddress(parts[1], chain)
+	if err != nil {
+		return "", err
+	}
+	if !cadenceIdentifier.MatchString(parts[2]) {
+		return "", fmt.Errorf("invalid contract name %q", parts[2])
+	}
+
+	return "A." + address + "." + parts[2], nil
+}
+
+func validateAddress(address string, chain flow.Chain) (string, error) {
+	hex := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(address), "0x"))
+	if hex == "" || len(hex) > 2*flow.AddressLength {
+		return "", fmt.Errorf("expected up to %d hex characters", 2*flow.AddressLength)
+	}
+	for i := 0; i < len(hex); i++ {
+		if !isHexDigit(hex[i]) {
+			return "", fmt.Errorf("invalid hex character %q", hex[i])
+		}
+	}
+
+	parsed := flow.HexToAddress(hex)
+	if chain != nil && !chain.IsValid(parsed) {
+		return "", fmt.Errorf("not a valid address on %s", chain.ChainID())
+	}
+
+	return parsed.Hex(), nil
+}
+
+// dedupe removes duplicate values, keeping the first occurrence of each.
+func dedupe(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	deduped := values[:0]
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		deduped = append(deduped, value)
+	}
+	return deduped
+}
+
+func (f EventFilter) isEmpty() bool {
+	return len(f.EventTypes) == 0 && len(f.Addresses) == 0 && len(f.Contracts) == 0
+}
+
+// matches returns true if the event type is selected by the filter, following the access node's
+// semantics: an event matches if its type, its contract or its contract's address is in the
+// filter. An empty filter matches all events.
+func (f EventFilter) matches(eventType flow.EventType) bool {
+	if f.isEmpty() {
+		return true
+	}
+
+	for _, t := range f.EventTypes {
+		if string(eventType) == t {
+			return true
+		}
+	}
+
+	// only account events (A.<address>.<contract>.<event>) have an address and contract
+	parts := strings.Split(string(eventType), ".")
+	if len(parts) != 4 || parts[0] != "A" {
+		return false
+	}
+
+	contract := strings.Join(parts[:3], ".")
+	for _, c := range f.Contracts {
+		if contract == c {
+			return true
+		}
+	}
+
+	for _, a := range f.Addresses {
+		if strings.EqualFold(strings.TrimPrefix(a, "0x"), parts[1]) {
+			return true
+		}
+	}
+
+	return false
+}
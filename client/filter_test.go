@@ -0,0 +1,138 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+)
+
+func TestEventFilterValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   EventFilter
+		expected EventFilter
+
+		// errs are the number of problems reported, if any
+		errs int
+	}{
+		{
+			name:     "empty",
+			filter:   EventFilter{},
+			expected: EventFilter{EventTypes: []string{}, Contracts: []string{}, Addresses: []string{}},
+		},
+		{
+			name: "normalized",
+			filter: EventFilter{
+				EventTypes: []string{" A.0x1654653399040A61.FlowToken.TokensDeposited ", "flow.AccountCreated"},
+				Contracts:  []string{"A.0xF8D6E0586B0A20C7.FungibleToken"},
+				Addresses:  []string{"0xF8D6E0586B0A20C7", " 1654653399040a61"},
+			},
+			expected: EventFilter{
+				EventTypes: []string{"A.1654653399040a61.FlowToken.TokensDeposited", "flow.AccountCreated"},
+				Contracts:  []string{"A.f8d6e0586b0a20c7.FungibleToken"},
+				Addresses:  []string{"f8d6e0586b0a20c7", "1654653399040a61"},
+			},
+		},
+		{
+			name: "short addresses padded",
+			filter: EventFilter{
+				EventTypes: []string{"A.1.FlowToken.TokensDeposited"},
+				Contracts:  []string{"A.0x2.FungibleToken"},
+				Addresses:  []string{"0x1"},
+			},
+			expected: EventFilter{
+				EventTypes: []string{"A.0000000000000001.FlowToken.TokensDeposited"},
+				Contracts:  []string{"A.0000000000000002.FungibleToken"},
+				Addresses:  []string{"0000000000000001"},
+			},
+		},
+		{
+			name: "duplicates removed after normalizing",
+			filter: EventFilter{
+				EventTypes: []string{
+					"A.1654653399040a61.FlowToken.TokensDeposited",
+					"A.0x1654653399040A61.FlowToken.TokensDeposited",
+					"flow.AccountCreated",
+					"A.1654653399040a61.FlowToken.TokensDeposited",
+				},
+				Contracts: []string{"A.1.FlowToken", "A.0000000000000001.FlowToken", "A.0x01.FlowToken"},
+				Addresses: []string{"0x1", "01", "0x0000000000000001", "0x2"},
+			},
+			expected: EventFilter{
+				EventTypes: []string{"A.1654653399040a61.FlowToken.TokensDeposited", "flow.AccountCreated"},
+				Contracts:  []string{"A.0000000000000001.FlowToken"},
+				Addresses:  []string{"0000000000000001", "0000000000000002"},
+			},
+		},
+		{
+			name: "names are case sensitive",
+			filter: EventFilter{
+				Contracts: []string{"A.1.FlowToken", "A.1.flowToken"},
+			},
+			expected: EventFilter{
+				EventTypes: []string{},
+				Contracts:  []string{"A.0000000000000001.FlowToken", "A.0000000000000001.flowToken"},
+				Addresses:  []string{},
+			},
+		},
+		{
+			name:   "event type without event name",
+			filter: EventFilter{EventTypes: []string{"A.1654653399040a61.FlowToken"}},
+			errs:   1,
+		},
+		{
+			name:   "flow event with address",
+			filter: EventFilter{EventTypes: []string{"flow.1.AccountCreated"}},
+			errs:   1,
+		},
+		{
+			name:   "invalid identifiers",
+			filter: EventFilter{EventTypes: []string{"A.1.Flow-Token.Deposited", "A.1.FlowToken.1Deposited"}},
+			errs:   2,
+		},
+		{
+			name:   "contract with event name",
+			filter: EventFilter{Contracts: []string{"A.1654653399040a61.FlowToken.TokensDeposited"}},
+			errs:   1,
+		},
+		{
+			name:   "invalid addresses",
+			filter: EventFilter{Addresses: []string{"", "0x", "0xzz", "0x1654653399040a6100"}},
+			errs:   4,
+		},
+		{
+			name: "all problems reported",
+			filter: EventFilter{
+				EventTypes: []string{"FlowToken.TokensDeposited", "flow.AccountCreated"},
+				Contracts:  []string{"FlowToken"},
+				Addresses:  []string{"0xg"},
+			},
+			errs: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			err := filter.Validate(nil)
+
+			if tt.errs > 0 {
+				require.Error(t, err)
+				assert.Len(t, multierr.Errors(err), tt.errs)
+
+				// the filter is left unchanged
+				assert.Equal(t, tt.filter, filter)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, filter)
+
+			// validating a normalized filter doesn't change it
+			require.NoError(t, filter.Validate(nil))
+			assert.Equal(t, tt.expected, filter)
+		})
+	}
+}
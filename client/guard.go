@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+)
+
+// GuardConfig configures the checks applied to every response received by a subscription.
+//
+// By default, heights must strictly increase, duplicate blocks are dropped, and execution data
+// subscriptions fail with an *ErrGap if a height is skipped. Event subscriptions may skip heights
+// with no matching events, so gaps are not checked for them.
+type GuardConfig struct {
+	// Disabled turns off all checks.
+	Disabled bool
+
+	// RepairGaps fetches skipped heights from the access node and delivers them in order,
+	// instead of failing the subscription.
+	RepairGaps bool
+
+	// CheckParentIDs looks up the header of each block, and checks that its height matches and
+	// its parent is the previously delivered block. This requires a request per block.
+	CheckParentIDs bool
+}
+
+// subscriptionGuard validates the ordering of responses received by a subscription.
+type subscriptionGuard[T BlockResponse] struct {
+	config    GuardConfig
+	allowGaps bool
+	log       Logger
+
+	// next is the next expected height, or 0 if unknown
+	next     uint64
+	previous T
+	started  bool
+
+	// repair returns the response for a skipped height. nil if repairs are not supported.
+	repair func(ctx context.Context, height uint64) (T, error)
+
+	// header returns the height and parent ID of a block. nil if parent checks are not supported.
+	header func(ctx context.Context, blockID flow.Identifier) (uint64, flow.Identifier, error)
+}
+
+// check validates the response, and returns the responses to deliver in order. This may be
+// empty if the response was a duplicate, or include repaired responses before it.
+func (g *subscriptionGuard[T]) check(ctx context.Context, response T) ([]T, error) {
+	if g.config.Disabled {
+		return []T{response}, nil
+	}
+
+	height := response.GetHeight()
+	if g.started && height <= g.previous.GetHeight() {
+		if height == g.previous.GetHeight() && response.GetBlockID() == g.previous.GetBlockID() {
+			g.log.Warn("dropping duplicate block", F("height", height), F("block_id", response.GetBlockID()))
+			return nil, nil
+		}
+		return nil, &ErrOutOfOrder{Previous: g.previous.GetHeight(), Height: height}
+	}
+
+	var responses []T
+	if !g.allowGaps && g.next > 0 && height > g.next {
+		if !g.config.RepairGaps || g.repair == nil {
+			return nil, &ErrGap{From: g.next, To: height - 1}
+		}
+
+		g.log.Warn("repairing gap in subscription", F("from", g.next), F("to", height-1))
+		for missing := g.next; missing < height; missing++ {
+			repaired, err := g.repair(ctx, missing)
+			if err != nil {
+				return nil, &ErrGap{From: missing, To: height - 1, Err: err}
+			}
+			if err := g.accept(ctx, repaired); err != nil {
+				return nil, err
+			}
+			responses = append(responses, repaired)
+		}
+	}
+
+	if err := g.accept(ctx, response); err != nil {
+		return nil, err
+	}
+
+	return append(responses, response), nil
+}
+
+// accept checks the response's parent if enabled, and records it as the previous response.
+func (g *subscriptionGuard[T]) accept(ctx context.Context, response T) error {
+	if g.config.CheckParentIDs && g.header != nil {
+		height, parentID, err := g.header(ctx, response.GetBlockID())
+		if err != nil {
+			return fmt.Errorf("could not get header for block %s: %w", response.GetBlockID(), err)
+		}
+		if height != response.GetHeight() {
+			return fmt.Errorf("block %s has height %d, but was received for height %d",
+				response.GetBlockID(), height, response.GetHeight())
+		}
+		if g.started && g.previous.GetHeight()+1 == height && parentID != g.previous.GetBlockID() {
+			return &ErrParentMismatch{
+				Height:   height,
+				BlockID:  response.GetBlockID(),
+				Expected: g.previous.GetBlockID(),
+				Actual:   parentID,
+			}
+		}
+	}
+
+	g.previous = response
+	g.started = true
+	g.next = response.GetHeight() + 1
+
+	return nil
+}
+
+// blockHeader returns the height and parent ID of the block with the given ID.
+func (c *ExecutionDataClient) blockHeader(ctx context.Context, blockID flow.Identifier) (uint64, flow.Identifier, error) {
+	resp, err := c.accessClient.GetBlockHeaderByID(ctx, &access.GetBlockHeaderByIDRequest{Id: blockID[:]})
+	if err != nil {
+		c.metrics.Error("get_block_header", err)
+		return 0, flow.ZeroID, err
+	}
+
+	return resp.GetBlock().GetHeight(), convert.MessageToIdentifier(resp.GetBlock().GetParentId()), nil
+}
+
+func (c *ExecutionDataClient) newEventsGuard() *subscriptionGuard[EventsResponse] {
+	return &subscriptionGuard[EventsResponse]{
+		config:    c.guard,
+		allowGaps: true,
+		log:       c.log,
+		header:    c.blockHeader,
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBlockID returns a block ID unique to the height and fork.
+func testBlockID(height uint64, fork byte) flow.Identifier {
+	return flow.Identifier{byte(height), byte(height >> 8), fork}
+}
+
+// testResponse returns an events response for the block at the height on the fork.
+func testResponse(height uint64, fork byte) EventsResponse {
+	return EventsResponse{Height: height, BlockID: testBlockID(height, fork)}
+}
+
+func TestSubscriptionGuard(t *testing.T) {
+	// header returns each block's parent on the same fork
+	header := func(_ context.Context, blockID flow.Identifier) (uint64, flow.Identifier, error) {
+		height := uint64(blockID[0]) | uint64(blockID[1])<<8
+		return height, testBlockID(height-1, blockID[2]), nil
+	}
+
+	repair := func(_ context.Context, height uint64) (EventsResponse, error) {
+		if height == 13 {
+			return EventsResponse{}, errors.New("not found")
+		}
+		return testResponse(height, 0), nil
+	}
+
+	tests := []struct {
+		name      string
+		config    GuardConfig
+		allowGaps bool
+		next      uint64
+		responses []EventsResponse
+
+		// delivered are the heights delivered before the error, if any
+		delivered []uint64
+		err       interface{}
+	}{
+		{
+			name:      "in order",
+			responses: []EventsResponse{testResponse(1, 0), testResponse(2, 0), testResponse(3, 0)},
+			delivered: []uint64{1, 2, 3},
+		},
+		{
+			name:      "duplicate dropped",
+			responses: []EventsResponse{testResponse(1, 0), testResponse(2, 0), testResponse(2, 0), testResponse(3, 0)},
+			delivered: []uint64{1, 2, 3},
+		},
+		{
+			name:      "same height different block",
+			responses: []EventsResponse{testResponse(1, 0), testResponse(2, 0), testResponse(2, 1)},
+			delivered: []uint64{1, 2},
+			err:       &ErrOutOfOrder{},
+		},
+		{
+			name:      "height decreases",
+			responses: []EventsResponse{testResponse(2, 0), testResponse(3, 0), testResponse(1, 0)},
+			delivered: []uint64{2, 3},
+			err:       &ErrOutOfOrder{},
+		},
+		{
+			name:      "gap",
+			responses: []EventsResponse{testResponse(1, 0), testResponse(4, 0)},
+			delivered: []uint64{1},
+			err:       &ErrGap{},
+		},
+		{
+			name:      "gap from start height",
+			next:      5,
+			responses: []EventsResponse{testResponse(7, 0)},
+			err:       &ErrGap{},
+		},
+		{
+			name:      "gap allowed",
+			allowGaps: true,
+			responses: []EventsResponse{testResponse(1, 0), testResponse(4, 0)},
+			delivered: []uint64{1, 4},
+		},
+		{
+			name:      "gap repaired",
+			config:    GuardConfig{RepairGaps: true},
+			responses: []EventsResponse{testResponse(1, 0), testResponse(4, 0), testResponse(5, 0)},
+			delivered: []uint64{1, 2, 3, 4, 5},
+		},
+		{
+			name:      "gap repair fails",
+			config:    GuardConfig{RepairGaps: true},
+			responses: []EventsResponse{testResponse(11, 0), testResponse(15, 0)},
+			delivered: []uint64{11},
+			err:       &ErrGap{},
+		},
+		{
+			name:      "parents match",
+			config:    GuardConfig{CheckParentIDs: true},
+			responses: []EventsResponse{testResponse(1, 0), testResponse(2, 0), testResponse(3, 0)},
+			delivered: []uint64{1, 2, 3},
+		},
+		{
+			name:      "parent mismatch",
+			config:    GuardConfig{CheckParentIDs: true},
+			responses: []EventsResponse{testResponse(1, 0), testResponse(2, 0), testResponse(3, 1)},
+			delivered: []uint64{1, 2},
+			err:       &ErrParentMismatch{},
+		},
+		{
+			name:      "parent not checked across gap",
+			config:    GuardConfig{CheckParentIDs: true},
+			allowGaps: true,
+			responses: []EventsResponse{testResponse(1, 0), testResponse(3, 1)},
+			delivered: []uint64{1, 3},
+		},
+		{
+			name:      "header height mismatch",
+			config:    GuardConfig{CheckParentIDs: true},
+			responses: []EventsResponse{{Height: 2, BlockID: testBlockID(3, 0)}},
+			err:       errors.New(""),
+		},
+		{
+			name:   "disabled",
+			config: GuardConfig{Disabled: true},
+			responses: []EventsResponse{
+				testResponse(2, 0), testResponse(2, 0), testResponse(1, 0), testResponse(5, 0),
+			},
+			delivered: []uint64{2, 2, 1, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &subscriptionGuard[EventsResponse]{
+				config:    tt.config,
+				allowGaps: tt.allowGaps,
+				log:       NoopLogger{},
+				next:      tt.next,
+				repair:    repair,
+				header:    header,
+			}
+
+			var delivered []uint64
+			var err error
+			for _, response := range tt.responses {
+				var responses []EventsResponse
+				responses, err = g.check(context.Background(), response)
+				if err != nil {
+					break
+				}
+				for _, r := range responses {
+					delivered = append(delivered, r.Height)
+				}
+			}
+
+			assert.Equal(t, tt.delivered, delivered)
+
+			switch expected := tt.err.(type) {
+			case nil:
+				assert.NoError(t, err)
+			case *ErrOutOfOrder:
+				assert.ErrorAs(t, err, &expected)
+			case *ErrGap:
+				assert.ErrorAs(t, err, &expected)
+			case *ErrParentMismatch:
+				assert.ErrorAs(t, err, &expected)
+			default:
+				require.Error(t, err)
+			}
+		})
+	}
+}
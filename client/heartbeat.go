@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// HeartbeatConfig configures heartbeats and stall detection for subscriptions.
+type HeartbeatConfig struct {
+	// Interval is the number of blocks after which the access node sends an events response,
+	// even if there were no matching events. 0 uses the access node's default.
+	Interval uint64
+
+	// SuppressHeartbeats drops events responses with no events instead of delivering them.
+	// They are still used to detect stalls.
+	SuppressHeartbeats bool
+
+	// StallTimeout is the maximum time to wait for a message from the access node before the
+	// subscription is considered stalled. Time spent waiting for the consumer to accept
+	// responses is not counted. 0 disables stall detection.
+	StallTimeout time.Duration
+
+	// ReconnectOnStall resubscribes from the next undelivered height when the subscription
+	// stalls, instead of failing with an *ErrStalled.
+	ReconnectOnStall bool
+}
+
+// heartbeatTracker decides when to deliver a response with no data as a heartbeat, for
+// subscriptions filtered by the client.
+type heartbeatTracker struct {
+	// interval is the number of blocks between heartbeats. 0 disables them.
+	interval uint64
+
+	// lastSent is the height of the last response sent
+	lastSent uint64
+}
+
+// due returns true if a heartbeat should be sent at the given height.
+func (h *heartbeatTracker) due(height uint64) bool {
+	return h.interval > 0 && height-h.lastSent >= h.interval
+}
+
+// sent records that a response was sent at the given height.
+func (h *heartbeatTracker) sent(height uint64) {
+	h.lastSent = height
+}
+
+type streamReceiver[M any] interface {
+	Recv() (M, error)
+}
+
+// watchedStream receives from a grpc stream, canceling it if no message is received within the
+// stall timeout and optionally resubscribing.
+type watchedStream[M any] struct {
+	ctx     context.Context
+	config  HeartbeatConfig
+	name    string
+	log     Logger
+	metrics Metrics
+
+	// subscribe opens a new stream. resume is true when resubscribing after a stall.
+	subscribe func(ctx context.Context, resume bool) (streamReceiver[M], error)
+
+	stream  streamReceiver[M]
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+func newWatchedStream[M any](
+	ctx context.Context,
+	c *ExecutionDataClient,
+	name string,
+	subscribe func(ctx context.Context, resume bool) (streamReceiver[M], error),
+) (*watchedStream[M], error) {
+	w := &watchedStream[M]{
+		ctx:       ctx,
+		config:    c.heartbeat,
+		name:      name,
+		log:       c.log,
+		metrics:   c.metrics,
+		subscribe: subscribe,
+	}
+
+	if err := w.open(false); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *watchedStream[M]) open(resume bool) error {
+	ctx, cancel := context.WithCancel(w.ctx)
+
+	stream, err := w.subscribe(ctx, resume)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	w.stream = stream
+	w.cancel = cancel
+	w.stalled.Store(false)
+
+	if w.config.StallTimeout > 0 {
+		// the timer is only armed while Recv is waiting for the stream
+		w.timer = time.AfterFunc(w.config.StallTimeout, func() {
+			w.stalled.Store(true)
+			cancel()
+		})
+		w.timer.Stop()
+	}
+
+	return nil
+}
+
+// Recv returns the next message from the stream. If the stream stalls, it either resubscribes
+// or returns an *ErrStalled.
+func (w *watchedStream[M]) Recv() (M, error) {
+	for {
+		if w.timer != nil {
+			w.timer.Reset(w.config.StallTimeout)
+		}
+		msg, err := w.stream.Recv()
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		if err == nil {
+			return msg, nil
+		}
+
+		if !w.stalled.Load() || w.ctx.Err() != nil {
+			return msg, err
+		}
+
+		if !w.config.ReconnectOnStall {
+			return msg, &ErrStalled{Timeout: w.config.StallTimeout}
+		}
+
+		w.log.Warn("subscription stalled, resubscribing", F("stream", w.name), F("timeout", w.config.StallTimeout))
+		w.metrics.Reconnect(w.name)
+
+		w.close()
+		if err := w.open(true); err != nil {
+			return msg, err
+		}
+	}
+}
+
+func (w *watchedStream[M]) close() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.cancel()
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStream returns queued messages, blocking until its context is done once they run out.
+type fakeStream struct {
+	ctx      context.Context
+	messages chan uint64
+}
+
+func (s *fakeStream) Recv() (uint64, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	select {
+	case <-s.ctx.Done():
+		return 0, s.ctx.Err()
+	case msg := <-s.messages:
+		return msg, nil
+	}
+}
+
+func TestWatchedStreamStall(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+
+	c := &ExecutionDataClient{
+		log:       NoopLogger{},
+		metrics:   NoopMetrics{},
+		heartbeat: HeartbeatConfig{StallTimeout: timeout},
+	}
+
+	messages := make(chan uint64, 2)
+	messages <- 1
+	messages <- 2
+
+	w, err := newWatchedStream(context.Background(), c, "test",
+		func(ctx context.Context, resume bool) (streamReceiver[uint64], error) {
+			return &fakeStream{ctx: ctx, messages: messages}, nil
+		},
+	)
+	require.NoError(t, err)
+	defer w.close()
+
+	msg, err := w.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), msg)
+
+	// a slow consumer is not a stall
+	time.Sleep(3 * timeout)
+
+	msg, err = w.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), msg)
+
+	// no message within the timeout is
+	_, err = w.Recv()
+	var stalled *ErrStalled
+	assert.True(t, errors.As(err, &stalled), "expected ErrStalled, got %v", err)
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultBlockIDCacheSize = 10_000
+
+// GetExecutionDataByHeight returns the BlockExecutionData for the sealed block at the given height.
+//
+// A *NotAvailableError is returned if the height is beyond the latest sealed block, or the
+// execution data for the block has not been indexed by the access node yet.
+func (c *ExecutionDataClient) GetExecutionDataByHeight(
+	ctx context.Context,
+	height uint64,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	blockID, err := c.GetSealedBlockID(ctx, height, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	execData, err := c.GetExecutionDataForBlockID(ctx, blockID, opts...)
+	if err != nil {
+		var notAvailable *NotAvailableError
+		if errors.As(err, &notAvailable) {
+			notAvailable.Height = height
+		}
+		return nil, err
+	}
+
+	return execData, nil
+}
+
+// GetSealedBlockID returns the ID of the sealed block at the given height.
+//
+// Sealed blocks are final, so results are cached and subsequent lookups for the same height
+// do not hit the access node.
+func (c *ExecutionDataClient) GetSealedBlockID(
+	ctx context.Context,
+	height uint64,
+	opts ...grpc.CallOption,
+) (flow.Identifier, error) {
+	if blockID, ok := c.blockIDs.get(height); ok {
+		return blockID, nil
+	}
+
+	// only heights that are sealed are safe to cache, so check against the latest sealed height,
+	// refreshing it from the access node only when the requested height is beyond it.
+	latestSealed := c.blockIDs.latestSealedHeight()
+	if height > latestSealed {
+		var err error
+		latestSealed, err = c.GetLatestSealedHeight(ctx, opts...)
+		if err != nil {
+			return flow.ZeroID, err
+		}
+
+		if height > latestSealed {
+			return flow.ZeroID, &NotAvailableError{
+				Height:             height,
+				LatestSealedHeight: latestSealed,
+			}
+		}
+
+		// the refresh caches the latest sealed block
+		if blockID, ok := c.blockIDs.get(height); ok {
+			return blockID, nil
+		}
+	}
+
+	resp, err := c.accessClient.GetBlockHeaderByHeight(ctx, &access.GetBlockHeaderByHeightRequest{Height: height}, opts...)
+	if err != nil {
+		c.metrics.Error("get_block_header", err)
+		if status.Code(err) == codes.NotFound {
+			return flow.ZeroID, &NotAvailableError{
+				Height:             height,
+				LatestSealedHeight: latestSealed,
+				Err:                err,
+			}
+		}
+		return flow.ZeroID, fmt.Errorf("could not get block header for height %d: %w", height, err)
+	}
+
+	blockID := convert.MessageToIdentifier(resp.GetBlock().GetId())
+	c.blockIDs.add(height, blockID)
+
+	return blockID, nil
+}
+
+// GetLatestSealedHeight returns the latest sealed height from the access node.
+func (c *ExecutionDataClient) GetLatestSealedHeight(ctx context.Context, opts ...grpc.CallOption) (uint64, error) {
+	resp, err := c.accessClient.GetLatestBlockHeader(ctx, &access.GetLatestBlockHeaderRequest{IsSealed: true}, opts...)
+	if err != nil {
+		c.metrics.Error("get_latest_block_header", err)
+		return 0, fmt.Errorf("could not get latest sealed block header: %w", err)
+	}
+
+	height := resp.GetBlock().GetHeight()
+	c.blockIDs.setLatestSealedHeight(height)
+	c.blockIDs.add(height, convert.MessageToIdentifier(resp.GetBlock().GetId()))
+	c.metrics.LatestSealedHeight(height)
+
+	return height, nil
+}
+
+// MonitorSealedHeight polls the latest sealed height at the given interval until the context is
+// canceled. This keeps the sealed height lag metric up to date while subscriptions are running.
+func (c *ExecutionDataClient) MonitorSealedHeight(ctx context.Context, interval time.Duration, opts ...grpc.CallOption) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.GetLatestSealedHeight(ctx, opts...); err != nil && ctx.Err() == nil {
+			c.log.Warn("could not get latest sealed height", F("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// blockIDCache is a bounded height to block ID cache for sealed blocks. When full, the oldest
+// inserted entry is evicted.
+type blockIDCache struct {
+	mu           sync.RWMutex
+	ids          map[uint64]flow.Identifier
+	order        []uint64
+	next         int
+	latestSealed uint64
+}
+
+func newBlockIDCache(size int) *blockIDCache {
+	return &blockIDCache{
+		ids:   make(map[uint64]flow.Identifier, size),
+		order: make([]uint64, 0, size),
+	}
+}
+
+func (c *blockIDCache) get(height uint64) (flow.Identifier, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	blockID, ok := c.ids[height]
+	return blockID, ok
+}
+
+func (c *blockIDCache) add(height uint64, blockID flow.Identifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.ids[height]; ok {
+		return
+	}
+
+	if len(c.order) < cap(c.order) {
+		c.order = append(c.order, height)
+	} else {
+		delete(c.ids, c.order[c.next])
+		c.order[c.next] = height
+		c.next = (c.next + 1) % len(c.order)
+	}
+
+	c.ids[height] = blockID
+}
+
+func (c *blockIDCache) latestSealedHeight() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.latestSealed
+}
+
+func (c *blockIDCache) setLatestSealedHeight(height uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if height > c.latestSealed {
+		c.latestSealed = height
+	}
+}
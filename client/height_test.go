@@ -0,0 +1,450 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"github.com/onflow/flow/protobuf/go/flow/executiondata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testAccessNode is an in-memory access node serving a single fork of blocks with IDs from
+// testBlockID. It implements the access and execution data API clients used by the client.
+type testAccessNode struct {
+	access.AccessAPIClient
+	executiondata.ExecutionDataAPIClient
+
+	mu sync.Mutex
+
+	// sealed is the latest sealed height. Later blocks are not found.
+	sealed uint64
+
+	// errs are returned by the next calls to each method, in order.
+	errs map[string][]error
+
+	// calls counts the calls to each method.
+	calls map[string]int
+
+	// events are the events in each block.
+	events map[uint64][]*entities.Event
+
+	// subscriptions are the requested start heights of the streams opened.
+	subscriptions []uint64
+
+	// streamFailHeight and streamErr make streams fail with the error when they reach the
+	// height, if it's set.
+	streamFailHeight uint64
+	streamErr        error
+
+	// delay is added to each request.
+	delay time.Duration
+
+	// rewind is the number of blocks before the requested height that streams start at, like a
+	// node replaying blocks that were already delivered.
+	rewind uint64
+
+	// sealedChanged is closed when the latest sealed height changes.
+	sealedChanged chan struct{}
+}
+
+func newTestAccessNode(sealed uint64) *testAccessNode {
+	return &testAccessNode{
+		sealed: sealed,
+		errs:   make(map[string][]error),
+		calls:  make(map[string]int),
+		events: make(map[uint64][]*entities.Event),
+
+		sealedChanged: make(chan struct{}),
+	}
+}
+
+// newTestClient returns a client backed by the node.
+func newTestClient(node *testAccessNode) *ExecutionDataClient {
+	return &ExecutionDataClient{
+		client:       node,
+		accessClient: node,
+		chain:        flow.Emulator.Chain(),
+		log:          NoopLogger{},
+		metrics:      NoopMetrics{},
+		tracer:       trace.NewNoopTracerProvider().Tracer(tracerName),
+		blockIDs:     newBlockIDCache(defaultBlockIDCacheSize),
+	}
+}
+
+// fail makes the next calls to the method return the errors, in order.
+func (n *testAccessNode) fail(method string, errs ...error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.errs[method] = append(n.errs[method], errs...)
+}
+
+// setSealed sets the latest sealed height.
+func (n *testAccessNode) setSealed(height uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sealed = height
+	close(n.sealedChanged)
+	n.sealedChanged = make(chan struct{})
+}
+
+// addEvents adds events of the given types to the block at the height.
+func (n *testAccessNode) addEvents(height uint64, eventTypes ...string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, eventType := range eventTypes {
+		n.events[height] = append(n.events[height], &entities.Event{
+			Type:       eventType,
+			EventIndex: uint32(len(n.events[height])),
+		})
+	}
+}
+
+// blockEvents returns the events in the block at the height with one of the types. All events
+// are returned if no types are given.
+func (n *testAccessNode) blockEvents(height uint64, eventTypes []string) []*entities.Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var events []*entities.Event
+	for _, event := range n.events[height] {
+		match := len(eventTypes) == 0
+		for _, eventType := range eventTypes {
+			match = match || event.GetType() == eventType
+		}
+		if match {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// subscribed returns the requested start heights of the streams opened.
+func (n *testAccessNode) subscribed() []uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]uint64(nil), n.subscriptions...)
+}
+
+// failStreamsAt makes streams fail with the error when they reach the height.
+func (n *testAccessNode) failStreamsAt(height uint64, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.streamFailHeight = height
+	n.streamErr = err
+}
+
+// streamFailure returns the error streams fail with at the height, if any.
+func (n *testAccessNode) streamFailure(height uint64) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.streamErr != nil && height >= n.streamFailHeight {
+		return n.streamErr
+	}
+	return nil
+}
+
+// waitSealed blocks until the height is sealed, or the context is done.
+func (n *testAccessNode) waitSealed(ctx context.Context, height uint64) error {
+	for {
+		n.mu.Lock()
+		sealed, changed := n.sealed, n.sealedChanged
+		n.mu.Unlock()
+
+		if height <= sealed {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-changed:
+		}
+	}
+}
+
+// subscribe records a stream opened at the start height, returning the error queued for the
+// method if any.
+func (n *testAccessNode) subscribe(method string, startHeight uint64, startBlockID []byte) (uint64, error) {
+	if _, err := n.call(method); err != nil {
+		return 0, err
+	}
+	if len(startBlockID) > 0 {
+		startHeight = testBlockHeight(startBlockID)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.subscriptions = append(n.subscriptions, startHeight)
+	if startHeight > n.rewind {
+		startHeight -= n.rewind
+	}
+	return startHeight, nil
+}
+
+// testNodeStream streams a response for each block from the start height, waiting for blocks to
+// be sealed.
+type testNodeStream[M any] struct {
+	grpc.ClientStream
+
+	ctx      context.Context
+	node     *testAccessNode
+	next     uint64
+	response func(height uint64) M
+}
+
+func (s *testNodeStream[M]) Recv() (M, error) {
+	var empty M
+	if err := s.node.streamFailure(s.next); err != nil {
+		return empty, err
+	}
+	if err := s.node.waitSealed(s.ctx, s.next); err != nil {
+		return empty, err
+	}
+	response := s.response(s.next)
+	s.next++
+	return response, nil
+}
+
+// callCount returns the number of calls made to the method.
+func (n *testAccessNode) callCount(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+// call records a call to the method, and returns the latest sealed height and the next error
+// queued for it, if any.
+func (n *testAccessNode) call(method string) (uint64, error) {
+	time.Sleep(n.delay)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.calls[method]++
+	if errs := n.errs[method]; len(errs) > 0 {
+		n.errs[method] = errs[1:]
+		return n.sealed, errs[0]
+	}
+	return n.sealed, nil
+}
+
+// testBlockHeight returns the height of a block ID from testBlockID.
+func testBlockHeight(blockID []byte) uint64 {
+	return uint64(blockID[0]) | uint64(blockID[1])<<8
+}
+
+func testBlockHeader(height uint64) *entities.BlockHeader {
+	blockID := testBlockID(height, 0)
+	parentID := testBlockID(height-1, 0)
+	return &entities.BlockHeader{Id: blockID[:], ParentId: parentID[:], Height: height}
+}
+
+func (n *testAccessNode) GetLatestBlockHeader(_ context.Context, _ *access.GetLatestBlockHeaderRequest, _ ...grpc.CallOption) (*access.BlockHeaderResponse, error) {
+	sealed, err := n.call("GetLatestBlockHeader")
+	if err != nil {
+		return nil, err
+	}
+	return &access.BlockHeaderResponse{Block: testBlockHeader(sealed)}, nil
+}
+
+func (n *testAccessNode) GetBlockHeaderByHeight(_ context.Context, req *access.GetBlockHeaderByHeightRequest, _ ...grpc.CallOption) (*access.BlockHeaderResponse, error) {
+	sealed, err := n.call("GetBlockHeaderByHeight")
+	if err != nil {
+		return nil, err
+	}
+	if req.GetHeight() > sealed {
+		return nil, status.Errorf(codes.NotFound, "block %d not found", req.GetHeight())
+	}
+	return &access.BlockHeaderResponse{Block: testBlockHeader(req.GetHeight())}, nil
+}
+
+func (n *testAccessNode) GetBlockHeaderByID(_ context.Context, req *access.GetBlockHeaderByIDRequest, _ ...grpc.CallOption) (*access.BlockHeaderResponse, error) {
+	sealed, err := n.call("GetBlockHeaderByID")
+	if err != nil {
+		return nil, err
+	}
+	height := testBlockHeight(req.GetId())
+	if height > sealed {
+		return nil, status.Errorf(codes.NotFound, "block %x not found", req.GetId())
+	}
+	return &access.BlockHeaderResponse{Block: testBlockHeader(height)}, nil
+}
+
+func (n *testAccessNode) GetExecutionDataByBlockID(_ context.Context, req *executiondata.GetExecutionDataByBlockIDRequest, _ ...grpc.CallOption) (*executiondata.GetExecutionDataByBlockIDResponse, error) {
+	sealed, err := n.call("GetExecutionDataByBlockID")
+	if err != nil {
+		return nil, err
+	}
+	if testBlockHeight(req.GetBlockId()) > sealed {
+		return nil, status.Errorf(codes.NotFound, "execution data for block %x not found", req.GetBlockId())
+	}
+	return &executiondata.GetExecutionDataByBlockIDResponse{
+		BlockExecutionData: n.blockExecutionData(testBlockHeight(req.GetBlockId())),
+	}, nil
+}
+
+// blockExecutionData returns the execution data for the block at the height, with its events in
+// a single chunk.
+func (n *testAccessNode) blockExecutionData(height uint64) *entities.BlockExecutionData {
+	blockID := testBlockID(height, 0)
+	m := &entities.BlockExecutionData{BlockId: blockID[:]}
+	if events := n.blockEvents(height, nil); len(events) > 0 {
+		m.ChunkExecutionData = []*entities.ChunkExecutionData{{Events: events}}
+	}
+	return m
+}
+
+func (n *testAccessNode) SubscribeExecutionData(ctx context.Context, req *executiondata.SubscribeExecutionDataRequest, _ ...grpc.CallOption) (executiondata.ExecutionDataAPI_SubscribeExecutionDataClient, error) {
+	startHeight, err := n.subscribe("SubscribeExecutionData", req.GetStartBlockHeight(), req.GetStartBlockId())
+	if err != nil {
+		return nil, err
+	}
+	return &testNodeStream[*executiondata.SubscribeExecutionDataResponse]{
+		ctx:  ctx,
+		node: n,
+		next: startHeight,
+		response: func(height uint64) *executiondata.SubscribeExecutionDataResponse {
+			return &executiondata.SubscribeExecutionDataResponse{
+				BlockHeight:        height,
+				BlockExecutionData: n.blockExecutionData(height),
+			}
+		},
+	}, nil
+}
+
+func (n *testAccessNode) SubscribeEvents(ctx context.Context, req *executiondata.SubscribeEventsRequest, _ ...grpc.CallOption) (executiondata.ExecutionDataAPI_SubscribeEventsClient, error) {
+	startHeight, err := n.subscribe("SubscribeEvents", req.GetStartBlockHeight(), req.GetStartBlockId())
+	if err != nil {
+		return nil, err
+	}
+	eventTypes := req.GetFilter().GetEventType()
+	return &testNodeStream[*executiondata.SubscribeEventsResponse]{
+		ctx:  ctx,
+		node: n,
+		next: startHeight,
+		response: func(height uint64) *executiondata.SubscribeEventsResponse {
+			blockID := testBlockID(height, 0)
+			return &executiondata.SubscribeEventsResponse{
+				BlockId:     blockID[:],
+				BlockHeight: height,
+				Events:      n.blockEvents(height, eventTypes),
+			}
+		},
+	}, nil
+}
+
+func TestGetExecutionDataByHeight(t *testing.T) {
+	t.Run("sealed height", func(t *testing.T) {
+		node := newTestAccessNode(10)
+		c := newTestClient(node)
+
+		execData, err := c.GetExecutionDataByHeight(context.Background(), 5)
+		require.NoError(t, err)
+		assert.Equal(t, testBlockID(5, 0), execData.BlockID)
+	})
+
+	t.Run("beyond sealed height", func(t *testing.T) {
+		node := newTestAccessNode(10)
+		c := newTestClient(node)
+
+		_, err := c.GetExecutionDataByHeight(context.Background(), 11)
+
+		var notAvailable *NotAvailableError
+		require.ErrorAs(t, err, &notAvailable)
+		assert.Equal(t, uint64(11), notAvailable.Height)
+		assert.Equal(t, uint64(10), notAvailable.LatestSealedHeight)
+		assert.Zero(t, node.callCount("GetBlockHeaderByHeight"))
+	})
+
+	t.Run("header not found", func(t *testing.T) {
+		node := newTestAccessNode(10)
+		node.fail("GetBlockHeaderByHeight", status.Error(codes.NotFound, "not found"))
+		c := newTestClient(node)
+
+		_, err := c.GetExecutionDataByHeight(context.Background(), 5)
+
+		var notAvailable *NotAvailableError
+		require.ErrorAs(t, err, &notAvailable)
+		assert.Equal(t, uint64(5), notAvailable.Height)
+		assert.Equal(t, codes.NotFound, status.Code(notAvailable.Err))
+	})
+
+	t.Run("execution data not indexed", func(t *testing.T) {
+		node := newTestAccessNode(10)
+		node.fail("GetExecutionDataByBlockID", status.Error(codes.NotFound, "not found"))
+		c := newTestClient(node)
+
+		_, err := c.GetExecutionDataByHeight(context.Background(), 5)
+
+		var notAvailable *NotAvailableError
+		require.ErrorAs(t, err, &notAvailable)
+		assert.Equal(t, uint64(5), notAvailable.Height)
+		assert.Equal(t, testBlockID(5, 0), notAvailable.BlockID)
+	})
+
+	t.Run("other errors not wrapped as not available", func(t *testing.T) {
+		node := newTestAccessNode(10)
+		node.fail("GetBlockHeaderByHeight", status.Error(codes.Internal, "boom"))
+		c := newTestClient(node)
+
+		_, err := c.GetExecutionDataByHeight(context.Background(), 5)
+		require.Error(t, err)
+
+		var notAvailable *NotAvailableError
+		assert.False(t, errors.As(err, &notAvailable))
+	})
+}
+
+func TestGetSealedBlockIDCache(t *testing.T) {
+	node := newTestAccessNode(10)
+	c := newTestClient(node)
+	ctx := context.Background()
+
+	blockID, err := c.GetSealedBlockID(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, testBlockID(5, 0), blockID)
+
+	// sealed blocks are final, so repeated lookups are served from the cache
+	blockID, err = c.GetSealedBlockID(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, testBlockID(5, 0), blockID)
+	assert.Equal(t, 1, node.callCount("GetBlockHeaderByHeight"))
+	assert.Equal(t, 1, node.callCount("GetLatestBlockHeader"))
+
+	// the latest sealed height is only refreshed for heights beyond it
+	_, err = c.GetSealedBlockID(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, 1, node.callCount("GetLatestBlockHeader"))
+
+	node.setSealed(12)
+	blockID, err = c.GetSealedBlockID(ctx, 12)
+	require.NoError(t, err)
+	assert.Equal(t, testBlockID(12, 0), blockID)
+	assert.Equal(t, 2, node.callCount("GetLatestBlockHeader"))
+	assert.Equal(t, 2, node.callCount("GetBlockHeaderByHeight"), "latest sealed block should be cached")
+}
+
+func TestBlockIDCacheEviction(t *testing.T) {
+	cache := newBlockIDCache(2)
+	cache.add(1, testBlockID(1, 0))
+	cache.add(2, testBlockID(2, 0))
+	cache.add(3, testBlockID(3, 0))
+
+	_, ok := cache.get(1)
+	assert.False(t, ok, "oldest entry should be evicted")
+	for _, height := range []uint64{2, 3} {
+		blockID, ok := cache.get(height)
+		assert.True(t, ok)
+		assert.Equal(t, testBlockID(height, 0), blockID)
+	}
+}
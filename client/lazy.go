@@ -0,0 +1,404 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"github.com/onflow/flow/protobuf/go/flow/executiondata"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// LazyExecutionData is a view of a BlockExecutionData message that converts each part of a
+// chunk only when it's first accessed. Consumers that only need events avoid decoding the
+// transactions and trie updates of large blocks.
+//
+// Parts are converted the same way as convert.MessageToBlockExecutionData, so the fully
+// converted data is identical to the eagerly converted data, and has the same execution data ID.
+//
+// Converted values reference the underlying message's buffers rather than copying them, and
+// are cached, so they are shared between callers and must not be modified. LazyExecutionData
+// is safe for concurrent use.
+type LazyExecutionData struct {
+	message *entities.BlockExecutionData
+	chain   flow.Chain
+	blockID flow.Identifier
+	chunks  []*lazyChunk
+}
+
+type lazyChunk struct {
+	message *entities.ChunkExecutionData
+
+	eventsOnce sync.Once
+	events     flow.EventsList
+
+	collectionOnce sync.Once
+	collection     *flow.Collection
+	collectionErr  error
+
+	trieUpdateOnce sync.Once
+	trieUpdate     *ledger.TrieUpdate
+	trieUpdateErr  error
+
+	chunkOnce sync.Once
+	chunk     *execution_data.ChunkExecutionData
+	chunkErr  error
+}
+
+// NewLazyExecutionData returns a LazyExecutionData view of the message.
+func NewLazyExecutionData(m *entities.BlockExecutionData, chain flow.Chain) *LazyExecutionData {
+	chunks := make([]*lazyChunk, len(m.GetChunkExecutionData()))
+	for i, chunk := range m.GetChunkExecutionData() {
+		chunks[i] = &lazyChunk{message: chunk}
+	}
+
+	return &LazyExecutionData{
+		message: m,
+		chain:   chain,
+		blockID: convert.MessageToIdentifier(m.GetBlockId()),
+		chunks:  chunks,
+	}
+}
+
+// Message returns the underlying protobuf message.
+func (d *LazyExecutionData) Message() *entities.BlockExecutionData {
+	return d.message
+}
+
+func (d *LazyExecutionData) BlockID() flow.Identifier {
+	return d.blockID
+}
+
+func (d *LazyExecutionData) NumChunks() int {
+	return len(d.chunks)
+}
+
+// NumEvents returns the number of events in the block without converting them.
+func (d *LazyExecutionData) NumEvents() int {
+	count := 0
+	for _, chunk := range d.chunks {
+		count += len(chunk.message.GetEvents())
+	}
+	return count
+}
+
+func (d *LazyExecutionData) chunk(index int) (*lazyChunk, error) {
+	if index < 0 || index >= len(d.chunks) {
+		return nil, fmt.Errorf("chunk index %d out of range [0, %d)", index, len(d.chunks))
+	}
+	return d.chunks[index], nil
+}
+
+// Events returns the events emitted in the chunk at the given index.
+func (d *LazyExecutionData) Events(index int) (flow.EventsList, error) {
+	chunk, err := d.chunk(index)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk.eventsOnce.Do(func() {
+		// chunks without events have nil events, as in convert.MessageToChunkExecutionData
+		if len(chunk.message.GetEvents()) > 0 {
+			chunk.events = convert.MessagesToEvents(chunk.message.GetEvents())
+		}
+	})
+
+	return chunk.events, nil
+}
+
+// AllEvents returns the events emitted in all chunks of the block, in order.
+func (d *LazyExecutionData) AllEvents() flow.EventsList {
+	events := make(flow.EventsList, 0, d.NumEvents())
+	for i := range d.chunks {
+		chunkEvents, _ := d.Events(i)
+		events = append(events, chunkEvents...)
+	}
+	return events
+}
+
+// Collection returns the collection of transactions executed in the chunk at the given index.
+// It's nil if the chunk has no transactions.
+func (d *LazyExecutionData) Collection(index int) (*flow.Collection, error) {
+	chunk, err := d.chunk(index)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk.collectionOnce.Do(func() {
+		// convert only the collection, using flow-go's conversion so that system transactions,
+		// which have no payer or proposer, are accepted
+		var converted *execution_data.ChunkExecutionData
+		converted, chunk.collectionErr = convert.MessageToChunkExecutionData(
+			&entities.ChunkExecutionData{Collection: chunk.message.GetCollection()},
+			d.chain,
+		)
+		if chunk.collectionErr == nil {
+			chunk.collection = converted.Collection
+		}
+	})
+
+	return chunk.collection, chunk.collectionErr
+}
+
+// TrieUpdate returns the register updates made by the chunk at the given index. It's nil if the
+// chunk has no trie update.
+func (d *LazyExecutionData) TrieUpdate(index int) (*ledger.TrieUpdate, error) {
+	chunk, err := d.chunk(index)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk.trieUpdateOnce.Do(func() {
+		if chunk.message.GetTrieUpdate() != nil {
+			chunk.trieUpdate, chunk.trieUpdateErr = convert.MessageToTrieUpdate(chunk.message.GetTrieUpdate())
+		}
+	})
+
+	return chunk.trieUpdate, chunk.trieUpdateErr
+}
+
+// Chunk returns the fully converted chunk at the given index.
+func (d *LazyExecutionData) Chunk(index int) (*execution_data.ChunkExecutionData, error) {
+	chunk, err := d.chunk(index)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk.chunkOnce.Do(func() {
+		chunk.chunk, chunk.chunkErr = convert.MessageToChunkExecutionData(chunk.message, d.chain)
+		if chunk.chunkErr != nil {
+			chunk.chunkErr = fmt.Errorf("could not convert chunk %d: %w", index, chunk.chunkErr)
+		}
+	})
+
+	return chunk.chunk, chunk.chunkErr
+}
+
+// BlockExecutionData returns the fully converted execution data for the block.
+func (d *LazyExecutionData) BlockExecutionData() (*execution_data.BlockExecutionData, error) {
+	chunks := make([]*execution_data.ChunkExecutionData, len(d.chunks))
+	for i := range d.chunks {
+		chunk, err := d.Chunk(i)
+		if err != nil {
+			return nil, err
+		}
+		chunks[i] = chunk
+	}
+
+	return &execution_data.BlockExecutionData{
+		BlockID:             d.blockID,
+		ChunkExecutionDatas: chunks,
+	}, nil
+}
+
+type LazyExecutionDataResponse struct {
+	BlockID       flow.Identifier
+	Height        uint64
+	ExecutionData *LazyExecutionData
+
+	receiveSpan
+}
+
+func (r LazyExecutionDataResponse) GetHeight() uint64 {
+	return r.Height
+}
+
+func (r LazyExecutionDataResponse) GetBlockID() flow.Identifier {
+	return r.BlockID
+}
+
+// SubscribeLazyExecutionData subscribes to execution data updates starting at the given block ID
+// or height, delivering a LazyExecutionData view of each block instead of converting it.
+//
+// If execution data verification is enabled, each block is fully converted to be verified.
+func (c *ExecutionDataClient) SubscribeLazyExecutionData(
+	ctx context.Context,
+	startBlockID flow.Identifier,
+	startHeight uint64,
+	opts ...grpc.CallOption,
+) (*Subscription[LazyExecutionDataResponse], error) {
+	if startBlockID != flow.ZeroID && startHeight > 0 {
+		return nil, fmt.Errorf("cannot specify both start block ID and start height")
+	}
+
+	guard := c.newLazyExecutionDataGuard(startHeight)
+
+	// next is the height after the last received response, used to resume after a stall
+	var next uint64
+
+	stream, err := newWatchedStream(ctx, c, streamExecutionData,
+		func(ctx context.Context, resume bool) (streamReceiver[*executiondata.SubscribeExecutionDataResponse], error) {
+			req := executiondata.SubscribeExecutionDataRequest{
+				EventEncodingVersion: c.eventEncoding(),
+			}
+			switch {
+			case resume && next > 0:
+				req.StartBlockHeight = next
+			case startBlockID != flow.ZeroID:
+				req.StartBlockId = startBlockID[:]
+			case startHeight > 0:
+				req.StartBlockHeight = startHeight
+			}
+
+			stream, err := c.client.SubscribeExecutionData(ctx, &req, opts...)
+			if err != nil {
+				c.metrics.Error("subscribe_execution_data", err)
+				return nil, err
+			}
+			return stream, nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := newStreamSubscription[LazyExecutionDataResponse](streamExecutionData, c.metrics)
+	c.log.Debug("subscribed to lazy execution data",
+		F("subscription_id", sub.ID()),
+		F("start_block_id", startBlockID),
+		F("start_height", startHeight),
+	)
+
+	go func() {
+		defer close(sub.ch)
+		defer stream.close()
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				c.log.Debug("execution data stream closed", F("subscription_id", sub.ID()))
+				return
+			}
+			if err != nil {
+				c.log.Warn("error receiving execution data", F("subscription_id", sub.ID()), F("error", err))
+				c.metrics.Error("subscribe_execution_data", err)
+				sub.err = fmt.Errorf("error receiving execution data: %w", err)
+				return
+			}
+
+			msgCtx, span := c.tracer.Start(ctx, "LazyExecutionDataSubscription.Receive",
+				trace.WithAttributes(
+					attrHeight.Int64(int64(resp.GetBlockHeight())),
+					attrPayloadSize.Int(proto.Size(resp)),
+				),
+			)
+
+			execData := NewLazyExecutionData(resp.GetBlockExecutionData(), c.chain)
+
+			c.log.Debug("received execution data",
+				F("subscription_id", sub.ID()),
+				F("height", resp.GetBlockHeight()),
+				F("block_id", execData.BlockID()),
+				F("chunks", execData.NumChunks()),
+			)
+
+			if c.verifyExecutionData {
+				err := c.verifyLazyExecutionData(msgCtx, execData, opts...)
+				if err != nil {
+					recordSpanError(span, err)
+					span.End()
+					c.log.Error("execution data failed verification",
+						F("subscription_id", sub.ID()),
+						F("height", resp.GetBlockHeight()),
+						F("block_id", execData.BlockID()),
+						F("error", err),
+					)
+					c.metrics.Error("verify_execution_data", err)
+					sub.err = fmt.Errorf("error verifying execution data: %w", err)
+					return
+				}
+			}
+
+			events := execData.NumEvents()
+			span.SetAttributes(
+				attrBlockID.String(execData.BlockID().String()),
+				attrChunks.Int(execData.NumChunks()),
+				attrEvents.Int(events),
+			)
+			c.metrics.BlockReceived(streamExecutionData, events, proto.Size(resp))
+
+			responses, err := guard.check(msgCtx, LazyExecutionDataResponse{
+				BlockID:       execData.BlockID(),
+				Height:        resp.BlockHeight,
+				ExecutionData: execData,
+				receiveSpan:   receiveSpan{spanContext: span.SpanContext()},
+			})
+			if err != nil {
+				recordSpanError(span, err)
+				span.End()
+				c.log.Error("execution data subscription failed validation", F("subscription_id", sub.ID()), F("error", err))
+				sub.err = err
+				return
+			}
+
+			next = resp.GetBlockHeight() + 1
+
+			for _, response := range responses {
+				if err := sub.send(ctx, response); err != nil {
+					span.End()
+					sub.err = err
+					return
+				}
+				c.metrics.DeliveredHeight(streamExecutionData, response.Height)
+			}
+			span.End()
+		}
+	}()
+
+	return sub, nil
+}
+
+// verifyLazyExecutionData fully converts the execution data and verifies it.
+func (c *ExecutionDataClient) verifyLazyExecutionData(
+	ctx context.Context,
+	execData *LazyExecutionData,
+	opts ...grpc.CallOption,
+) error {
+	converted, err := execData.BlockExecutionData()
+	if err != nil {
+		c.metrics.Error("convert_execution_data", err)
+		return fmt.Errorf("could not convert execution data: %w", err)
+	}
+
+	return c.VerifyExecutionData(ctx, converted, opts...)
+}
+
+func (c *ExecutionDataClient) newLazyExecutionDataGuard(startHeight uint64) *subscriptionGuard[LazyExecutionDataResponse] {
+	return &subscriptionGuard[LazyExecutionDataResponse]{
+		config: c.guard,
+		log:    c.log,
+		next:   startHeight,
+		repair: func(ctx context.Context, height uint64) (LazyExecutionDataResponse, error) {
+			blockID, err := c.GetSealedBlockID(ctx, height)
+			if err != nil {
+				return LazyExecutionDataResponse{}, err
+			}
+			m, err := c.getExecutionDataMessage(ctx, blockID)
+			if err != nil {
+				return LazyExecutionDataResponse{}, err
+			}
+
+			execData := NewLazyExecutionData(m, c.chain)
+			if c.verifyExecutionData {
+				if err := c.verifyLazyExecutionData(ctx, execData); err != nil {
+					return LazyExecutionDataResponse{}, err
+				}
+			}
+
+			return LazyExecutionDataResponse{
+				BlockID:       execData.BlockID(),
+				Height:        height,
+				ExecutionData: execData,
+			}, nil
+		},
+		header: c.blockHeader,
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// benchmarkExecutionDataMessage returns an execution data message with the given number of
+// chunks, each with a collection, events and register updates, followed by a system chunk.
+func benchmarkExecutionDataMessage(chunks, transactions, events, registers int) *entities.BlockExecutionData {
+	blockID := flow.Identifier{1}
+	payer := flow.HexToAddress("e467b9dd11fa00df")
+	m := &entities.BlockExecutionData{BlockId: blockID[:]}
+
+	for c := 0; c < chunks; c++ {
+		chunk := &entities.ChunkExecutionData{
+			Collection: &entities.ExecutionDataCollection{},
+			TrieUpdate: &entities.TrieUpdate{RootHash: bytes.Repeat([]byte{byte(c)}, 32)},
+		}
+
+		for i := 0; i < transactions; i++ {
+			chunk.Collection.Transactions = append(chunk.Collection.Transactions, &entities.Transaction{
+				Script:           bytes.Repeat([]byte("a"), 512),
+				ReferenceBlockId: blockID[:],
+				GasLimit:         9999,
+				Payer:            payer.Bytes(),
+			})
+		}
+
+		for i := 0; i < events; i++ {
+			chunk.Events = append(chunk.Events, &entities.Event{
+				Type:             "A.0000000000000001.Contract.Event",
+				TransactionId:    blockID[:],
+				TransactionIndex: uint32(i % transactions),
+				EventIndex:       uint32(i),
+				Payload:          bytes.Repeat([]byte("p"), 256),
+			})
+		}
+
+		for i := 0; i < registers; i++ {
+			chunk.TrieUpdate.Paths = append(chunk.TrieUpdate.Paths, bytes.Repeat([]byte{byte(i)}, 32))
+			chunk.TrieUpdate.Payloads = append(chunk.TrieUpdate.Payloads, &entities.Payload{
+				KeyPart: []*entities.KeyPart{
+					{Type: 0, Value: make([]byte, flow.AddressLength)},
+					{Type: 2, Value: []byte("key")},
+				},
+				Value: bytes.Repeat([]byte("v"), 128),
+			})
+		}
+
+		m.ChunkExecutionData = append(m.ChunkExecutionData, chunk)
+	}
+
+	// the system chunk's transaction has no payer or proposer, and the chunk has no events or
+	// trie update
+	m.ChunkExecutionData = append(m.ChunkExecutionData, &entities.ChunkExecutionData{
+		Collection: &entities.ExecutionDataCollection{
+			Transactions: []*entities.Transaction{{
+				Script:           []byte("system"),
+				ReferenceBlockId: blockID[:],
+				GasLimit:         9999,
+				Authorizers:      [][]byte{payer.Bytes()},
+			}},
+		},
+	})
+
+	return m
+}
+
+// BenchmarkExecutionDataDecoding compares converting whole blocks with reading only the events
+// through a LazyExecutionData view.
+func BenchmarkExecutionDataDecoding(b *testing.B) {
+	m := benchmarkExecutionDataMessage(10, 20, 50, 100)
+	chain := flow.Mainnet.Chain()
+
+	b.Run("eager", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := convert.MessageToBlockExecutionData(m, chain); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("lazy full", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewLazyExecutionData(m, chain).BlockExecutionData(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("lazy events", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewLazyExecutionData(m, chain).AllEvents()
+		}
+	})
+}
+
+func TestLazyExecutionDataMatchesConversion(t *testing.T) {
+	m := benchmarkExecutionDataMessage(3, 2, 4, 3)
+	chain := flow.Mainnet.Chain()
+
+	expected, err := convert.MessageToBlockExecutionData(m, chain)
+	require.NoError(t, err)
+
+	d := NewLazyExecutionData(m, chain)
+
+	// parts converted before the whole block are the same as those in the converted chunks
+	system := len(m.ChunkExecutionData) - 1
+	for i, chunk := range expected.ChunkExecutionDatas {
+		collection, err := d.Collection(i)
+		require.NoError(t, err)
+		assert.Equal(t, chunk.Collection, collection, "chunk %d", i)
+
+		trieUpdate, err := d.TrieUpdate(i)
+		require.NoError(t, err)
+		assert.Equal(t, chunk.TrieUpdate, trieUpdate, "chunk %d", i)
+
+		events, err := d.Events(i)
+		require.NoError(t, err)
+		assert.Equal(t, chunk.Events, events, "chunk %d", i)
+	}
+	trieUpdate, err := d.TrieUpdate(system)
+	require.NoError(t, err)
+	assert.Nil(t, trieUpdate)
+	events, err := d.Events(system)
+	require.NoError(t, err)
+	assert.Nil(t, events)
+
+	actual, err := d.BlockExecutionData()
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestLazyExecutionDataChunkIndex(t *testing.T) {
+	d := NewLazyExecutionData(benchmarkExecutionDataMessage(2, 1, 1, 1), flow.Mainnet.Chain())
+
+	assert.Equal(t, 3, d.NumChunks())
+	assert.Equal(t, 2, d.NumEvents())
+
+	for _, index := range []int{-1, 3} {
+		_, err := d.Events(index)
+		assert.Error(t, err, "chunk %d", index)
+		_, err = d.Collection(index)
+		assert.Error(t, err, "chunk %d", index)
+		_, err = d.TrieUpdate(index)
+		assert.Error(t, err, "chunk %d", index)
+	}
+}
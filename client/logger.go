@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/exp/slog"
+)
+
+// Field is a key/value pair attached to a log message.
+type Field struct {
+	Key   string
+	Value any
+}
+
+func F(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger is the structured logger used by the clients. The default logger discards all messages.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// NoopLogger is a Logger that discards all messages.
+type NoopLogger struct{}
+
+var _ Logger = NoopLogger{}
+
+func (NoopLogger) Debug(string, ...Field) {}
+func (NoopLogger) Info(string, ...Field)  {}
+func (NoopLogger) Warn(string, ...Field)  {}
+func (NoopLogger) Error(string, ...Field) {}
+
+// ZerologLogger adapts a zerolog.Logger to Logger.
+type ZerologLogger struct {
+	log zerolog.Logger
+}
+
+var _ Logger = (*ZerologLogger)(nil)
+
+func NewZerologLogger(log zerolog.Logger) *ZerologLogger {
+	return &ZerologLogger{log: log}
+}
+
+func (l *ZerologLogger) Debug(msg string, fields ...Field) {
+	zerologEvent(l.log.Debug(), fields).Msg(msg)
+}
+
+func (l *ZerologLogger) Info(msg string, fields ...Field) {
+	zerologEvent(l.log.Info(), fields).Msg(msg)
+}
+
+func (l *ZerologLogger) Warn(msg string, fields ...Field) {
+	zerologEvent(l.log.Warn(), fields).Msg(msg)
+}
+
+func (l *ZerologLogger) Error(msg string, fields ...Field) {
+	zerologEvent(l.log.Error(), fields).Msg(msg)
+}
+
+func zerologEvent(event *zerolog.Event, fields []Field) *zerolog.Event {
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok {
+			event = event.AnErr(f.Key, err)
+			continue
+		}
+		event = event.Interface(f.Key, f.Value)
+	}
+	return event
+}
+
+// SlogLogger adapts a slog.Logger to Logger.
+type SlogLogger struct {
+	log *slog.Logger
+}
+
+var _ Logger = (*SlogLogger)(nil)
+
+func NewSlogLogger(log *slog.Logger) *SlogLogger {
+	return &SlogLogger{log: log}
+}
+
+func (l *SlogLogger) Debug(msg string, fields ...Field) {
+	l.log.LogAttrs(context.Background(), slog.LevelDebug, msg, slogAttrs(fields)...)
+}
+
+func (l *SlogLogger) Info(msg string, fields ...Field) {
+	l.log.LogAttrs(context.Background(), slog.LevelInfo, msg, slogAttrs(fields)...)
+}
+
+func (l *SlogLogger) Warn(msg string, fields ...Field) {
+	l.log.LogAttrs(context.Background(), slog.LevelWarn, msg, slogAttrs(fields)...)
+}
+
+func (l *SlogLogger) Error(msg string, fields ...Field) {
+	l.log.LogAttrs(context.Background(), slog.LevelError, msg, slogAttrs(fields)...)
+}
+
+func slogAttrs(fields []Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	return attrs
+}
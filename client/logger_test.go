@@ -0,0 +1,74 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+func TestLoggerAdapters(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// logger returns a logger writing JSON lines to the buffer, with the keys used for the
+		// level and message
+		logger func(buf *bytes.Buffer) (log Logger, levelKey string, msgKey string)
+	}{
+		{
+			name: "zerolog",
+			logger: func(buf *bytes.Buffer) (Logger, string, string) {
+				return NewZerologLogger(zerolog.New(buf).Level(zerolog.DebugLevel)), "level", "message"
+			},
+		},
+		{
+			name: "slog",
+			logger: func(buf *bytes.Buffer) (Logger, string, string) {
+				handler := slog.HandlerOptions{Level: slog.LevelDebug}.NewJSONHandler(buf)
+				return NewSlogLogger(slog.New(handler)), "level", "msg"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log, levelKey, msgKey := tt.logger(&buf)
+
+			log.Debug("debug message", F("height", 10))
+			log.Info("info message", F("block_id", "abc"))
+			log.Warn("warn message", F("error", errors.New("boom")))
+			log.Error("error message", F("count", 3), F("ok", true))
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 4)
+
+			expected := []struct {
+				level  string
+				msg    string
+				fields map[string]interface{}
+			}{
+				{"debug", "debug message", map[string]interface{}{"height": float64(10)}},
+				{"info", "info message", map[string]interface{}{"block_id": "abc"}},
+				{"warn", "warn message", map[string]interface{}{"error": "boom"}},
+				{"error", "error message", map[string]interface{}{"count": float64(3), "ok": true}},
+			}
+			for i, line := range lines {
+				var entry map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+
+				assert.Equal(t, expected[i].level, strings.ToLower(entry[levelKey].(string)), line)
+				assert.Equal(t, expected[i].msg, entry[msgKey], line)
+				for key, value := range expected[i].fields {
+					assert.Equal(t, value, entry[key], "field %s in %s", key, line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/status"
+)
+
+const metricsNamespace = "execdata_client"
+
+// stream names used to label metrics
+const (
+	streamExecutionData = "execution_data"
+	streamEvents        = "events"
+	streamRestEvents    = "rest_events"
+)
+
+// Metrics collects metrics from clients and subscriptions. The default collector discards all
+// metrics.
+type Metrics interface {
+	// BlockReceived records a block received on a stream, with its event count and encoded size.
+	BlockReceived(stream string, events int, bytes int)
+
+	// ConversionDuration records the time taken to convert a received message.
+	ConversionDuration(stream string, duration time.Duration)
+
+	// DeliveredHeight records the latest height delivered to the consumer of a stream.
+	DeliveredHeight(stream string, height uint64)
+
+	// LatestSealedHeight records the latest sealed height known to the client.
+	LatestSealedHeight(height uint64)
+
+	// Reconnect records that a stream was re-established.
+	Reconnect(stream string)
+
+	// Backpressure records the time spent waiting for the consumer to accept a response.
+	Backpressure(stream string, duration time.Duration)
+
+	// Error records an error returned by an operation.
+	Error(operation string, err error)
+
+	// CacheLookup records a lookup in the given cache tier, and whether it was a hit.
+	CacheLookup(tier string, hit bool)
+}
+
+// NoopMetrics is a Metrics collector that discards all metrics.
+type NoopMetrics struct{}
+
+var _ Metrics = NoopMetrics{}
+
+func (NoopMetrics) BlockReceived(string, int, int)           {}
+func (NoopMetrics) ConversionDuration(string, time.Duration) {}
+func (NoopMetrics) DeliveredHeight(string, uint64)           {}
+func (NoopMetrics) LatestSealedHeight(uint64)                {}
+func (NoopMetrics) Reconnect(string)                         {}
+func (NoopMetrics) Backpressure(string, time.Duration)       {}
+func (NoopMetrics) Error(string, error)                      {}
+func (NoopMetrics) CacheLookup(string, bool)                 {}
+
+// PrometheusMetrics is a Metrics collector backed by prometheus.
+type PrometheusMetrics struct {
+	blocks       *prometheus.CounterVec
+	events       *prometheus.CounterVec
+	bytes        *prometheus.CounterVec
+	conversion   *prometheus.HistogramVec
+	lag          *prometheus.GaugeVec
+	sealedHeight prometheus.Gauge
+	reconnects   *prometheus.CounterVec
+	backpressure *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	cache        *prometheus.CounterVec
+
+	mu        sync.Mutex
+	sealed    uint64
+	delivered map[string]uint64
+}
+
+var _ Metrics = (*PrometheusMetrics)(nil)
+
+// NewPrometheusMetrics creates a PrometheusMetrics collector and registers it on the given registerer.
+func NewPrometheusMetrics(registerer prometheus.Registerer) (*PrometheusMetrics, error) {
+	m := &PrometheusMetrics{
+		blocks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "blocks_received_total",
+			Help:      "number of blocks received",
+		}, []string{"stream"}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "events_received_total",
+			Help:      "number of events received",
+		}, []string{"stream"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "received_bytes_total",
+			Help:      "number of encoded message bytes received",
+		}, []string{"stream"}),
+		conversion: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "conversion_duration_seconds",
+			Help:      "time taken to convert received messages",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"stream"}),
+		lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "sealed_height_lag",
+			Help:      "number of blocks between the latest sealed height and the latest delivered height",
+		}, []string{"stream"}),
+		sealedHeight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "latest_sealed_height",
+			Help:      "latest sealed height known to the client",
+		}),
+		reconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "reconnects_total",
+			Help:      "number of times a stream was re-established",
+		}, []string{"stream"}),
+		backpressure: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "backpressure_seconds_total",
+			Help:      "time spent waiting for consumers to accept responses",
+		}, []string{"stream"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "errors_total",
+			Help:      "number of errors by operation and gRPC code",
+		}, []string{"operation", "code"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_lookups_total",
+			Help:      "number of cache lookups by tier and result",
+		}, []string{"tier", "result"}),
+		delivered: make(map[string]uint64),
+	}
+
+	collectors := []prometheus.Collector{
+		m.blocks,
+		m.events,
+		m.bytes,
+		m.conversion,
+		m.lag,
+		m.sealedHeight,
+		m.reconnects,
+		m.backpressure,
+		m.errors,
+		m.cache,
+	}
+	for _, c := range collectors {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *PrometheusMetrics) BlockReceived(stream string, events int, bytes int) {
+	m.blocks.WithLabelValues(stream).Inc()
+	m.events.WithLabelValues(stream).Add(float64(events))
+	m.bytes.WithLabelValues(stream).Add(float64(bytes))
+}
+
+func (m *PrometheusMetrics) ConversionDuration(stream string, duration time.Duration) {
+	m.conversion.WithLabelValues(stream).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) DeliveredHeight(stream string, height uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.delivered[stream] = height
+	m.updateLag(stream)
+}
+
+func (m *PrometheusMetrics) LatestSealedHeight(height uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if height <= m.sealed {
+		return
+	}
+
+	m.sealed = height
+	m.sealedHeight.Set(float64(height))
+	for stream := range m.delivered {
+		m.updateLag(stream)
+	}
+}
+
+// updateLag must be called while holding the lock.
+func (m *PrometheusMetrics) updateLag(stream string) {
+	delivered := m.delivered[stream]
+	if m.sealed == 0 || delivered >= m.sealed {
+		m.lag.WithLabelValues(stream).Set(0)
+		return
+	}
+	m.lag.WithLabelValues(stream).Set(float64(m.sealed - delivered))
+}
+
+func (m *PrometheusMetrics) Reconnect(stream string) {
+	m.reconnects.WithLabelValues(stream).Inc()
+}
+
+func (m *PrometheusMetrics) Backpressure(stream string, duration time.Duration) {
+	m.backpressure.WithLabelValues(stream).Add(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) Error(operation string, err error) {
+	m.errors.WithLabelValues(operation, status.Code(err).String()).Inc()
+}
+
+func (m *PrometheusMetrics) CacheLookup(tier string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cache.WithLabelValues(tier, result).Inc()
+}
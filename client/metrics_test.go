@@ -0,0 +1,106 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	newMetrics := func(t *testing.T) *PrometheusMetrics {
+		m, err := NewPrometheusMetrics(prometheus.NewRegistry())
+		require.NoError(t, err)
+		return m
+	}
+
+	t.Run("stream labels", func(t *testing.T) {
+		m := newMetrics(t)
+
+		m.BlockReceived(streamExecutionData, 3, 100)
+		m.BlockReceived(streamExecutionData, 2, 50)
+		m.BlockReceived(streamEvents, 1, 10)
+		m.Reconnect(streamEvents)
+		m.Backpressure(streamEvents, 2*time.Second)
+		m.ConversionDuration(streamExecutionData, time.Millisecond)
+
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.blocks.WithLabelValues(streamExecutionData)))
+		assert.Equal(t, float64(5), testutil.ToFloat64(m.events.WithLabelValues(streamExecutionData)))
+		assert.Equal(t, float64(150), testutil.ToFloat64(m.bytes.WithLabelValues(streamExecutionData)))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.blocks.WithLabelValues(streamEvents)))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.reconnects.WithLabelValues(streamEvents)))
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.backpressure.WithLabelValues(streamEvents)))
+		assert.Equal(t, 1, testutil.CollectAndCount(m.conversion))
+	})
+
+	t.Run("errors labelled by operation and code", func(t *testing.T) {
+		m := newMetrics(t)
+
+		m.Error("get_execution_data", status.Error(codes.NotFound, "not found"))
+		m.Error("get_execution_data", status.Error(codes.NotFound, "not found"))
+		m.Error("get_execution_data", errors.New("boom"))
+		m.Error("subscribe_events", status.Error(codes.Unavailable, "unavailable"))
+
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.errors.WithLabelValues("get_execution_data", "NotFound")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.errors.WithLabelValues("get_execution_data", "Unknown")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.errors.WithLabelValues("subscribe_events", "Unavailable")))
+	})
+
+	t.Run("cache lookups", func(t *testing.T) {
+		m := newMetrics(t)
+
+		m.CacheLookup("memory", true)
+		m.CacheLookup("memory", false)
+		m.CacheLookup("disk", true)
+
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.cache.WithLabelValues("memory", "hit")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.cache.WithLabelValues("memory", "miss")))
+		assert.Equal(t, float64(1), testutil.ToFloat64(m.cache.WithLabelValues("disk", "hit")))
+	})
+
+	t.Run("sealed height lag", func(t *testing.T) {
+		m := newMetrics(t)
+		lag := func(stream string) float64 {
+			return testutil.ToFloat64(m.lag.WithLabelValues(stream))
+		}
+
+		// no lag is reported until the sealed height is known
+		m.DeliveredHeight(streamEvents, 90)
+		assert.Equal(t, float64(0), lag(streamEvents))
+
+		m.LatestSealedHeight(100)
+		assert.Equal(t, float64(100), testutil.ToFloat64(m.sealedHeight))
+		assert.Equal(t, float64(10), lag(streamEvents))
+
+		m.DeliveredHeight(streamExecutionData, 98)
+		assert.Equal(t, float64(2), lag(streamExecutionData))
+
+		// delivering past the known sealed height doesn't report a negative lag
+		m.DeliveredHeight(streamEvents, 101)
+		assert.Equal(t, float64(0), lag(streamEvents))
+
+		// older sealed heights are ignored
+		m.LatestSealedHeight(95)
+		assert.Equal(t, float64(100), testutil.ToFloat64(m.sealedHeight))
+		assert.Equal(t, float64(2), lag(streamExecutionData))
+
+		m.LatestSealedHeight(110)
+		assert.Equal(t, float64(9), lag(streamEvents))
+		assert.Equal(t, float64(12), lag(streamExecutionData))
+	})
+
+	t.Run("registered once", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		_, err := NewPrometheusMetrics(registry)
+		require.NoError(t, err)
+
+		_, err = NewPrometheusMetrics(registry)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"crypto/tls"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+type config struct {
+	dialOptions []grpc.DialOption
+	logger      Logger
+	metrics     Metrics
+
+	maxReceiveMessageSize int
+	compression           string
+	keepalive             keepalive.ClientParameters
+	callTimeout           time.Duration
+	userAgent             string
+	rateLimit             *RateLimitConfig
+	retry                 *RetryConfig
+
+	tlsConfig     *tls.Config
+	apiKeyHeader  string
+	apiKey        string
+	tokenProvider TokenProvider
+	insecureAuth  bool
+
+	verifyExecutionData bool
+	verifyEvents        bool
+
+	guard     GuardConfig
+	heartbeat HeartbeatConfig
+
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+func defaultConfig() *config {
+	return &config{
+		logger:         NoopLogger{},
+		metrics:        NoopMetrics{},
+		tracerProvider: trace.NewNoopTracerProvider(),
+
+		maxReceiveMessageSize: defaultMaxReceiveMessageSize,
+		keepalive:             defaultKeepalive,
+		callTimeout:           defaultCallTimeout,
+		userAgent:             defaultUserAgent,
+		apiKeyHeader:          defaultAPIKeyHeader,
+	}
+}
+
+// Option configures an ExecutionDataClient or RestClient. Options that only apply to one of
+// the clients are ignored by the other.
+type Option func(*config)
+
+// WithDialOptions sets the grpc.DialOptions used to connect to the access node. If none are
+// provided, insecure transport credentials are used. Dial options take precedence over the
+// equivalent typed options.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *config) {
+		c.dialOptions = append(c.dialOptions, opts...)
+	}
+}
+
+// WithMaxReceiveMessageSize sets the maximum size of a message received from the access node.
+// Defaults to 128MiB.
+func WithMaxReceiveMessageSize(bytes int) Option {
+	return func(c *config) {
+		c.maxReceiveMessageSize = bytes
+	}
+}
+
+// WithCompression sets the compressor used for requests to the access node, one of
+// CompressionNone, CompressionGzip or CompressionZstd. Access nodes that support the compressor
+// use it for their responses. Defaults to CompressionNone.
+func WithCompression(compressor string) Option {
+	return func(c *config) {
+		c.compression = compressor
+	}
+}
+
+// WithKeepalive sets the keepalive parameters for the connection to the access node. Defaults
+// to pinging after 30s without activity, with a 20s timeout.
+func WithKeepalive(params keepalive.ClientParameters) Option {
+	return func(c *config) {
+		c.keepalive = params
+	}
+}
+
+// WithCallTimeout sets the timeout for unary requests made without a context deadline.
+// 0 disables the timeout. Defaults to 30s.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.callTimeout = timeout
+	}
+}
+
+// WithUserAgent sets the user agent sent to the access node. Defaults to "execdata-client".
+func WithUserAgent(userAgent string) Option {
+	return func(c *config) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRateLimit limits the rate of requests made to the access node. See RateLimitConfig.
+func WithRateLimit(rateLimit RateLimitConfig) Option {
+	return func(c *config) {
+		c.rateLimit = &rateLimit
+	}
+}
+
+// WithRetry retries requests that fail with a retryable error. See RetryConfig for the defaults.
+func WithRetry(retry RetryConfig) Option {
+	return func(c *config) {
+		c.retry = &retry
+	}
+}
+
+// WithTLS connects to the access node using TLS with the given config. For the RestClient,
+// subscriptions use secure websockets.
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(c *config) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithAPIKey sends the API key in the given header with every request. If header is empty,
+// "x-api-key" is used.
+//
+// Credentials are only sent over TLS unless WithInsecureAuth is used.
+func WithAPIKey(header string, key string) Option {
+	return func(c *config) {
+		if header != "" {
+			c.apiKeyHeader = header
+		}
+		c.apiKey = key
+	}
+}
+
+// WithTokenProvider sends a bearer token from the provider in the authorization header with every
+// request and stream.
+//
+// Credentials are only sent over TLS unless WithInsecureAuth is used.
+func WithTokenProvider(provider TokenProvider) Option {
+	return func(c *config) {
+		c.tokenProvider = provider
+	}
+}
+
+// WithInsecureAuth allows API keys and tokens to be sent over insecure connections, for example
+// to a local access node.
+func WithInsecureAuth() Option {
+	return func(c *config) {
+		c.insecureAuth = true
+	}
+}
+
+// WithLogger sets the logger used by the client. Defaults to a no-op logger.
+func WithLogger(logger Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithMetrics sets the metrics collector used by the client and its subscriptions.
+// Defaults to a no-op collector.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *config) {
+		c.metrics = metrics
+	}
+}
+
+// WithTracing enables OpenTelemetry tracing using the given tracer provider. The trace context
+// is propagated to the access node in grpc metadata using the global text map propagator.
+func WithTracing(tracerProvider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tracerProvider
+		if c.propagator == nil {
+			c.propagator = otel.GetTextMapPropagator()
+		}
+	}
+}
+
+// WithPropagator sets the propagator used to send the trace context to the access node.
+// Defaults to the global text map propagator when tracing is enabled.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// WithExecutionDataVerification enables verification of all execution data received by the
+// client against the block's sealed execution result. See ExecutionDataClient.VerifyExecutionData.
+//
+// When enabled, event payloads are requested in their original CCF encoding.
+func WithExecutionDataVerification() Option {
+	return func(c *config) {
+		c.verifyExecutionData = true
+	}
+}
+
+// WithEventVerification enables verification of all events received by SubscribeEvents against
+// the event collection hashes in the block's sealed execution result. See
+// ExecutionDataClient.VerifyEvents.
+//
+// When enabled, event payloads are requested in their original CCF encoding.
+func WithEventVerification() Option {
+	return func(c *config) {
+		c.verifyEvents = true
+	}
+}
+
+// WithSubscriptionGuard configures the ordering checks applied to subscription responses.
+// See GuardConfig for the defaults.
+func WithSubscriptionGuard(guard GuardConfig) Option {
+	return func(c *config) {
+		c.guard = guard
+	}
+}
+
+// WithHeartbeat configures event subscription heartbeats and stall detection for subscriptions.
+func WithHeartbeat(heartbeat HeartbeatConfig) Option {
+	return func(c *config) {
+		c.heartbeat = heartbeat
+	}
+}
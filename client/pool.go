@@ -0,0 +1,515 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"go.uber.org/multierr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultMaxHeightLag        = 10
+	defaultMaxErrorRate        = 0.5
+	defaultFailoverDelay       = time.Second
+	defaultMaxFailoverDelay    = 30 * time.Second
+	defaultMaxFailovers        = 5
+	defaultFailoverCooldown    = 30 * time.Second
+
+	// weight given to the latest sample in the moving averages of latency and error rate
+	poolEWMAWeight = 0.2
+)
+
+type PoolConfig struct {
+	// HealthCheckInterval is the interval between health checks of each node. Defaults to 10s.
+	HealthCheckInterval time.Duration
+
+	// MaxHeightLag is the maximum number of blocks a node's latest sealed height may be behind
+	// the highest sealed height in the pool before it's considered unhealthy. Defaults to 10.
+	MaxHeightLag uint64
+
+	// MaxErrorRate is the error rate above which a node is considered unhealthy. Defaults to 0.5.
+	MaxErrorRate float64
+
+	// FailoverDelay is the delay before resubscribing after a subscription fails. The delay
+	// doubles for each consecutive failover without a response being delivered. Defaults to 1s.
+	FailoverDelay time.Duration
+
+	// MaxFailoverDelay is the maximum delay before resubscribing. Defaults to 30s.
+	MaxFailoverDelay time.Duration
+
+	// MaxFailovers is the maximum number of consecutive failovers without a response being
+	// delivered before the subscription fails. Defaults to 5.
+	MaxFailovers int
+
+	// FailoverCooldown is the time a node is considered unhealthy after a subscription on it
+	// fails. Defaults to 30s.
+	FailoverCooldown time.Duration
+}
+
+// NodeHealth is a snapshot of the health of an access node in the pool.
+type NodeHealth struct {
+	Address            string
+	LatestSealedHeight uint64
+	Latency            time.Duration
+	ErrorRate          float64
+	Healthy            bool
+	LastError          error
+}
+
+type poolNode struct {
+	address string
+	client  *ExecutionDataClient
+
+	mu        sync.Mutex
+	sealed    uint64
+	latency   time.Duration
+	errorRate float64
+	lastErr   error
+
+	// cooldown is the time until which the node is considered unhealthy after a failure
+	cooldown time.Time
+}
+
+func (n *poolNode) record(latency time.Duration, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err != nil {
+		n.errorRate = n.errorRate*(1-poolEWMAWeight) + poolEWMAWeight
+		n.lastErr = err
+		return
+	}
+
+	n.errorRate = n.errorRate * (1 - poolEWMAWeight)
+	if n.latency == 0 {
+		n.latency = latency
+	} else {
+		n.latency = time.Duration(float64(n.latency)*(1-poolEWMAWeight) + float64(latency)*poolEWMAWeight)
+	}
+}
+
+// fail records a failed subscription, marking the node unhealthy until the cooldown has passed.
+func (n *poolNode) fail(err error, cooldown time.Duration) {
+	n.record(0, err)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cooldown = time.Now().Add(cooldown)
+}
+
+// ExecutionDataPool is a client that distributes requests across multiple access nodes.
+// Request/response calls are routed to the healthiest node and retried on other nodes if they
+// fail. Subscriptions fail over to another node mid-stream, resuming at the next height.
+type ExecutionDataPool struct {
+	nodes   []*poolNode
+	config  PoolConfig
+	log     Logger
+	metrics Metrics
+
+	cancel context.CancelFunc
+}
+
+// NewExecutionDataPool returns a pool connected to each of the given addresses. The options are
+// applied to the client for each node.
+func NewExecutionDataPool(
+	addresses []string,
+	chain flow.Chain,
+	config PoolConfig,
+	opts ...Option,
+) (*ExecutionDataPool, error) {
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("at least one address is required")
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if config.MaxHeightLag == 0 {
+		config.MaxHeightLag = defaultMaxHeightLag
+	}
+	if config.MaxErrorRate <= 0 {
+		config.MaxErrorRate = defaultMaxErrorRate
+	}
+	if config.FailoverDelay <= 0 {
+		config.FailoverDelay = defaultFailoverDelay
+	}
+	if config.MaxFailoverDelay <= 0 {
+		config.MaxFailoverDelay = defaultMaxFailoverDelay
+	}
+	if config.MaxFailovers <= 0 {
+		config.MaxFailovers = defaultMaxFailovers
+	}
+	if config.FailoverCooldown <= 0 {
+		config.FailoverCooldown = defaultFailoverCooldown
+	}
+
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	nodes := make([]*poolNode, 0, len(addresses))
+	for _, address := range addresses {
+		client, err := NewExecutionDataClientWithOptions(address, chain, opts...)
+		if err != nil {
+			for _, n := range nodes {
+				_ = n.client.Close()
+			}
+			return nil, fmt.Errorf("could not create client for %s: %w", address, err)
+		}
+		nodes = append(nodes, &poolNode{
+			address: address,
+			client:  client,
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ExecutionDataPool{
+		nodes:   nodes,
+		config:  config,
+		log:     cfg.logger,
+		metrics: cfg.metrics,
+		cancel:  cancel,
+	}
+
+	p.checkHealth(ctx)
+	go p.healthCheckLoop(ctx)
+
+	return p, nil
+}
+
+// Close stops the pool's health checks and closes the connections to the access nodes.
+func (p *ExecutionDataPool) Close() error {
+	p.cancel()
+
+	var errs error
+	for _, n := range p.nodes {
+		if err := n.client.Close(); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("%s: %w", n.address, err))
+		}
+	}
+	return errs
+}
+
+// Health returns the current health of each node, ordered from healthiest to least healthy.
+func (p *ExecutionDataPool) Health() []NodeHealth {
+	var maxSealed uint64
+	for _, n := range p.nodes {
+		n.mu.Lock()
+		if n.sealed > maxSealed {
+			maxSealed = n.sealed
+		}
+		n.mu.Unlock()
+	}
+
+	now := time.Now()
+	health := make([]NodeHealth, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		n.mu.Lock()
+		healthy := n.sealed+p.config.MaxHeightLag >= maxSealed &&
+			n.errorRate <= p.config.MaxErrorRate &&
+			!now.Before(n.cooldown)
+		health = append(health, NodeHealth{
+			Address:            n.address,
+			LatestSealedHeight: n.sealed,
+			Latency:            n.latency,
+			ErrorRate:          n.errorRate,
+			Healthy:            healthy,
+			LastError:          n.lastErr,
+		})
+		n.mu.Unlock()
+	}
+
+	sort.SliceStable(health, func(i, j int) bool {
+		if health[i].Healthy != health[j].Healthy {
+			return health[i].Healthy
+		}
+		return health[i].Latency < health[j].Latency
+	})
+
+	return health
+}
+
+// ranked returns the nodes ordered from healthiest to least healthy. The excluded node, if any,
+// is always last, so it's only used if every other node fails.
+func (p *ExecutionDataPool) ranked(exclude *poolNode) []*poolNode {
+	byAddress := make(map[string]*poolNode, len(p.nodes))
+	for _, n := range p.nodes {
+		byAddress[n.address] = n
+	}
+
+	health := p.Health()
+	nodes := make([]*poolNode, 0, len(health))
+	for _, h := range health {
+		if n := byAddress[h.Address]; n != exclude {
+			nodes = append(nodes, n)
+		}
+	}
+	if exclude != nil {
+		nodes = append(nodes, exclude)
+	}
+	return nodes
+}
+
+func (p *ExecutionDataPool) healthCheckLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.checkHealth(ctx)
+		}
+	}
+}
+
+func (p *ExecutionDataPool) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, n := range p.nodes {
+		wg.Add(1)
+		go func(n *poolNode) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, p.config.HealthCheckInterval)
+			defer cancel()
+
+			start := time.Now()
+			height, err := n.client.GetLatestSealedHeight(ctx)
+			n.record(time.Since(start), err)
+			if err != nil {
+				p.log.Warn("access node health check failed", F("address", n.address), F("error", err))
+				return
+			}
+
+			n.mu.Lock()
+			n.sealed = height
+			n.mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+}
+
+// GetExecutionDataForBlockID returns the BlockExecutionData for the given block ID from the
+// healthiest node, falling back to the other nodes on failure.
+func (p *ExecutionDataPool) GetExecutionDataForBlockID(
+	ctx context.Context,
+	blockID flow.Identifier,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	return poolCall(ctx, p, func(client *ExecutionDataClient) (*execution_data.BlockExecutionData, error) {
+		return client.GetExecutionDataForBlockID(ctx, blockID, opts...)
+	})
+}
+
+// GetExecutionDataByHeight returns the BlockExecutionData for the sealed block at the given
+// height from the healthiest node, falling back to the other nodes on failure.
+func (p *ExecutionDataPool) GetExecutionDataByHeight(
+	ctx context.Context,
+	height uint64,
+	opts ...grpc.CallOption,
+) (*execution_data.BlockExecutionData, error) {
+	return poolCall(ctx, p, func(client *ExecutionDataClient) (*execution_data.BlockExecutionData, error) {
+		return client.GetExecutionDataByHeight(ctx, height, opts...)
+	})
+}
+
+func poolCall[T any](ctx context.Context, p *ExecutionDataPool, call func(*ExecutionDataClient) (T, error)) (T, error) {
+	var errs error
+	for _, n := range p.ranked(nil) {
+		start := time.Now()
+		result, err := call(n.client)
+		if err == nil {
+			n.record(time.Since(start), nil)
+			return result, nil
+		}
+
+		// not available errors are expected for recent blocks and don't count against the node
+		if !IsNotAvailable(err) {
+			n.record(time.Since(start), err)
+		}
+		if ctx.Err() != nil || !isTransient(err) {
+			return result, err
+		}
+		errs = multierr.Append(errs, fmt.Errorf("%s: %w", n.address, err))
+	}
+
+	var zero T
+	return zero, fmt.Errorf("all access nodes failed: %w", errs)
+}
+
+// SubscribeExecutionData subscribes to execution data starting at the given height, or the latest
+// block if startHeight is 0. If the node serving the subscription fails, the subscription fails
+// over to the healthiest remaining node, resuming at the next undelivered height.
+func (p *ExecutionDataPool) SubscribeExecutionData(
+	ctx context.Context,
+	startHeight uint64,
+	opts ...grpc.CallOption,
+) (*Subscription[ExecutionDataResponse], error) {
+	return poolSubscribe(ctx, p, streamExecutionData, startHeight,
+		func(client *ExecutionDataClient, height uint64) (*Subscription[ExecutionDataResponse], error) {
+			return client.SubscribeExecutionData(ctx, flow.ZeroID, height, opts...)
+		},
+	)
+}
+
+// SubscribeEvents subscribes to events starting at the given height, or the latest block if
+// startHeight is 0. If the node serving the subscription fails, the subscription fails over to
+// the healthiest remaining node, resuming at the next undelivered height.
+func (p *ExecutionDataPool) SubscribeEvents(
+	ctx context.Context,
+	startHeight uint64,
+	filter EventFilter,
+	opts ...grpc.CallOption,
+) (*Subscription[EventsResponse], error) {
+	return poolSubscribe(ctx, p, streamEvents, startHeight,
+		func(client *ExecutionDataClient, height uint64) (*Subscription[EventsResponse], error) {
+			return client.SubscribeEvents(ctx, flow.ZeroID, height, filter, opts...)
+		},
+	)
+}
+
+func poolSubscribe[T BlockResponse](
+	ctx context.Context,
+	p *ExecutionDataPool,
+	stream string,
+	startHeight uint64,
+	subscribe func(client *ExecutionDataClient, height uint64) (*Subscription[T], error),
+) (*Subscription[T], error) {
+	// failing over before anything is delivered must resume from the same block, so pin the
+	// latest sealed height instead of relying on each node's default.
+	if startHeight == 0 {
+		latest, err := poolCall(ctx, p, func(client *ExecutionDataClient) (uint64, error) {
+			return client.GetLatestSealedHeight(ctx)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not get latest sealed height: %w", err)
+		}
+		startHeight = latest
+	}
+
+	node, upstream, err := poolSubscribeNode(p, startHeight, nil, subscribe)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := newStreamSubscription[T](stream, p.metrics)
+	go func() {
+		defer close(sub.ch)
+
+		next := startHeight
+		delay := p.config.FailoverDelay
+
+		// failovers is the number of consecutive failovers without a response being delivered
+		failovers := 0
+
+		for {
+			for response := range upstream.Channel() {
+				// drop any heights that were already delivered by the previous node
+				if response.GetHeight() < next {
+					continue
+				}
+
+				if err := sub.send(ctx, response); err != nil {
+					sub.err = err
+					return
+				}
+				next = response.GetHeight() + 1
+				delay = p.config.FailoverDelay
+				failovers = 0
+			}
+
+			if ctx.Err() != nil {
+				sub.err = ctx.Err()
+				return
+			}
+
+			err := upstream.Err()
+			if err == nil {
+				err = fmt.Errorf("subscription closed")
+			}
+			node.fail(err, p.config.FailoverCooldown)
+
+			failovers++
+			if failovers > p.config.MaxFailovers {
+				sub.err = fmt.Errorf("subscription failed after %d failovers: %w", p.config.MaxFailovers, err)
+				return
+			}
+
+			p.log.Warn("subscription failed, failing over to another access node",
+				F("subscription_id", sub.ID()),
+				F("address", node.address),
+				F("next_height", next),
+				F("failovers", failovers),
+				F("error", err),
+			)
+
+			select {
+			case <-ctx.Done():
+				sub.err = ctx.Err()
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > p.config.MaxFailoverDelay {
+				delay = p.config.MaxFailoverDelay
+			}
+
+			node, upstream, err = poolSubscribeNode(p, next, node, subscribe)
+			if err != nil {
+				sub.err = err
+				return
+			}
+			p.metrics.Reconnect(stream)
+		}
+	}()
+
+	return sub, nil
+}
+
+// poolSubscribeNode subscribes on the healthiest node that accepts the subscription, trying the
+// excluded node last.
+func poolSubscribeNode[T any](
+	p *ExecutionDataPool,
+	height uint64,
+	exclude *poolNode,
+	subscribe func(client *ExecutionDataClient, height uint64) (*Subscription[T], error),
+) (*poolNode, *Subscription[T], error) {
+	var errs error
+	for _, n := range p.ranked(exclude) {
+		sub, err := subscribe(n.client, height)
+		if err == nil {
+			return n, sub, nil
+		}
+		n.record(0, err)
+		errs = multierr.Append(errs, fmt.Errorf("%s: %w", n.address, err))
+	}
+
+	return nil, nil, fmt.Errorf("could not subscribe on any access node: %w", errs)
+}
+
+// isTransient returns true if the error is likely to succeed when retried later or on another node.
+func isTransient(err error) bool {
+	if IsNotAvailable(err) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestPool returns a pool of the nodes, ranked in the given order while healthy.
+func newTestPool(config PoolConfig, nodes ...*testAccessNode) *ExecutionDataPool {
+	p := &ExecutionDataPool{
+		config:  config,
+		log:     NoopLogger{},
+		metrics: NoopMetrics{},
+		cancel:  func() {},
+	}
+	for i, node := range nodes {
+		p.nodes = append(p.nodes, &poolNode{
+			address: string(rune('a' + i)),
+			client:  newTestClient(node),
+			sealed:  node.sealed,
+			latency: time.Duration(i+1) * time.Millisecond,
+		})
+	}
+	return p
+}
+
+// testPoolConfig fails over quickly, and keeps failed nodes unhealthy for the test.
+var testPoolConfig = PoolConfig{
+	MaxHeightLag:     10,
+	MaxErrorRate:     0.5,
+	FailoverDelay:    time.Millisecond,
+	MaxFailoverDelay: 10 * time.Millisecond,
+	MaxFailovers:     3,
+	FailoverCooldown: time.Minute,
+}
+
+// poolHeights receives count responses from the subscription.
+func poolHeights(t *testing.T, sub *Subscription[EventsResponse], count int) []uint64 {
+	t.Helper()
+
+	var heights []uint64
+	for len(heights) < count {
+		select {
+		case response, ok := <-sub.Channel():
+			require.True(t, ok, "subscription closed: %v", sub.Err())
+			heights = append(heights, response.Height)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for response")
+		}
+	}
+	return heights
+}
+
+func TestPoolSubscriptionFailover(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	t.Run("resumes at the next height on another node", func(t *testing.T) {
+		a := newTestAccessNode(20)
+		a.failStreamsAt(13, unavailable)
+		b := newTestAccessNode(20)
+		p := newTestPool(testPoolConfig, a, b)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sub, err := p.SubscribeEvents(ctx, 10, EventFilter{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{10, 11, 12, 13, 14, 15}, poolHeights(t, sub, 6))
+		assert.Equal(t, []uint64{10}, a.subscribed())
+		assert.Equal(t, []uint64{13}, b.subscribed())
+
+		// the failed node is unhealthy while it cools down
+		health := p.Health()
+		assert.Equal(t, "b", health[0].Address)
+		assert.Equal(t, "a", health[1].Address)
+		assert.False(t, health[1].Healthy)
+		assert.Equal(t, codes.Unavailable, status.Code(health[1].LastError))
+	})
+
+	t.Run("heights replayed by the new node are dropped", func(t *testing.T) {
+		a := newTestAccessNode(20)
+		a.failStreamsAt(13, unavailable)
+		b := newTestAccessNode(20)
+		b.rewind = 2
+		p := newTestPool(testPoolConfig, a, b)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sub, err := p.SubscribeEvents(ctx, 10, EventFilter{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{10, 11, 12, 13, 14}, poolHeights(t, sub, 5))
+	})
+
+	t.Run("start height pinned before anything is delivered", func(t *testing.T) {
+		a := newTestAccessNode(10)
+		a.failStreamsAt(0, unavailable)
+		b := newTestAccessNode(15)
+		p := newTestPool(testPoolConfig, a, b)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// the latest sealed height is taken from the healthiest node
+		sub, err := p.SubscribeEvents(ctx, 0, EventFilter{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []uint64{10, 11}, poolHeights(t, sub, 2))
+		assert.Equal(t, []uint64{10}, a.subscribed())
+		assert.Equal(t, []uint64{10}, b.subscribed())
+	})
+
+	t.Run("fails after max failovers with backoff", func(t *testing.T) {
+		a := newTestAccessNode(20)
+		a.failStreamsAt(0, unavailable)
+		b := newTestAccessNode(20)
+		b.failStreamsAt(0, unavailable)
+
+		config := testPoolConfig
+		config.FailoverDelay = 20 * time.Millisecond
+		config.MaxFailoverDelay = 30 * time.Millisecond
+		config.MaxFailovers = 2
+		p := newTestPool(config, a, b)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+		sub, err := p.SubscribeEvents(ctx, 10, EventFilter{})
+		require.NoError(t, err)
+
+		for range sub.Channel() {
+			t.Fatal("unexpected response")
+		}
+
+		// the delay doubles between failovers, up to the max
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+		assert.ErrorContains(t, sub.Err(), "failed after 2 failovers")
+
+		// each failover prefers the node that didn't just fail
+		assert.Equal(t, []uint64{10, 10}, a.subscribed())
+		assert.Equal(t, []uint64{10}, b.subscribed())
+	})
+
+	t.Run("cooldown expires", func(t *testing.T) {
+		a := newTestAccessNode(20)
+		p := newTestPool(testPoolConfig, a)
+
+		p.nodes[0].fail(status.Error(codes.Unavailable, "unavailable"), 20*time.Millisecond)
+		assert.False(t, p.Health()[0].Healthy)
+
+		time.Sleep(30 * time.Millisecond)
+		assert.True(t, p.Health()[0].Healthy)
+	})
+}
+
+func TestPoolCall(t *testing.T) {
+	t.Run("transient errors retried on the next node", func(t *testing.T) {
+		a := newTestAccessNode(20)
+		a.fail("GetExecutionDataByBlockID", status.Error(codes.Unavailable, "unavailable"))
+		b := newTestAccessNode(20)
+		p := newTestPool(testPoolConfig, a, b)
+
+		execData, err := p.GetExecutionDataForBlockID(context.Background(), testBlockID(5, 0))
+		require.NoError(t, err)
+		assert.Equal(t, testBlockID(5, 0), execData.BlockID)
+		assert.Equal(t, 1, b.callCount("GetExecutionDataByBlockID"))
+	})
+
+	t.Run("not available doesn't count against the node", func(t *testing.T) {
+		a := newTestAccessNode(5)
+		b := newTestAccessNode(20)
+		p := newTestPool(testPoolConfig, a, b)
+
+		execData, err := p.GetExecutionDataByHeight(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, testBlockID(10, 0), execData.BlockID)
+		assert.Zero(t, p.nodes[0].errorRate)
+	})
+
+	t.Run("permanent errors returned", func(t *testing.T) {
+		a := newTestAccessNode(20)
+		a.fail("GetExecutionDataByBlockID", status.Error(codes.InvalidArgument, "invalid"))
+		b := newTestAccessNode(20)
+		p := newTestPool(testPoolConfig, a, b)
+
+		_, err := p.GetExecutionDataForBlockID(context.Background(), testBlockID(5, 0))
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Zero(t, b.callCount("GetExecutionDataByBlockID"))
+	})
+}
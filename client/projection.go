@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"go.uber.org/multierr"
+)
+
+// contractImport matches import declarations of the form `import Name from 0xaddress`
+var contractImport = regexp.MustCompile(`import\s+(\w+)\s+from\s+0x([0-9a-fA-F]+)`)
+
+// Projection selects the parts of a block's execution data that touch a set of accounts or
+// contracts.
+//
+// Projected execution data is a subset of the original, so it cannot be verified against the
+// execution result, and event and transaction indexes refer to positions in the full block.
+type Projection struct {
+	// Addresses selects transactions proposed, paid for or authorized by the accounts, events
+	// emitted by their contracts, and register updates in their storage.
+	Addresses []flow.Address
+
+	// Contracts selects transactions importing the contracts, events emitted by them, and
+	// updates to their code. Contracts are identified as A.<address>.<name>.
+	Contracts []string
+}
+
+// Validate checks that the projection's contracts are of the form A.<address>.<Contract> and
+// that its addresses are valid for the chain. If chain is nil, addresses are only checked for
+// format.
+//
+// Contracts are normalized and deduplicated as in EventFilter.Validate. All problems found are
+// returned together.
+func (p *Projection) Validate(chain flow.Chain) error {
+	filter := EventFilter{Contracts: p.Contracts}
+	errs := filter.Validate(chain)
+
+	if chain != nil {
+		for _, address := range p.Addresses {
+			if !chain.IsValid(address) {
+				errs = multierr.Append(errs, fmt.Errorf("invalid address %q: not a valid address on %s", address.Hex(), chain.ChainID()))
+			}
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+
+	p.Contracts = filter.Contracts
+	return nil
+}
+
+// Apply returns the execution data trimmed to the transactions, events and register updates
+// selected by the projection, or nil if nothing was selected. Chunks with nothing selected are
+// removed. An empty projection returns the execution data unchanged.
+//
+// An error is returned if the projection fails validation.
+func (p Projection) Apply(execData *execution_data.BlockExecutionData) (*execution_data.BlockExecutionData, error) {
+	if err := p.Validate(nil); err != nil {
+		return nil, err
+	}
+
+	return p.matcher().apply(execData), nil
+}
+
+// projectionMatcher holds the projection in forms that are cheap to match against.
+type projectionMatcher struct {
+	addresses map[flow.Address]struct{}
+	events    EventFilter
+
+	// contracts maps each contract's address to its names
+	contracts map[flow.Address][]string
+}
+
+func (p Projection) matcher() *projectionMatcher {
+	m := &projectionMatcher{
+		addresses: make(map[flow.Address]struct{}, len(p.Addresses)),
+		contracts: make(map[flow.Address][]string, len(p.Contracts)),
+		events:    EventFilter{Contracts: p.Contracts},
+	}
+
+	for _, address := range p.Addresses {
+		m.addresses[address] = struct{}{}
+		m.events.Addresses = append(m.events.Addresses, address.Hex())
+	}
+
+	// contracts have been validated, so are of the form A.<address>.<Contract>
+	for _, contract := range p.Contracts {
+		parts := strings.Split(contract, ".")
+		address := flow.HexToAddress(parts[1])
+		m.contracts[address] = append(m.contracts[address], parts[2])
+	}
+
+	return m
+}
+
+// apply returns the execution data trimmed to the selected parts, or nil if nothing was
+// selected. A matcher for an empty projection returns the execution data unchanged.
+func (m *projectionMatcher) apply(execData *execution_data.BlockExecutionData) *execution_data.BlockExecutionData {
+	if m.isEmpty() {
+		return execData
+	}
+
+	var chunks []*execution_data.ChunkExecutionData
+	for _, chunk := range execData.ChunkExecutionDatas {
+		if projected := m.chunk(chunk); projected != nil {
+			chunks = append(chunks, projected)
+		}
+	}
+
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	return &execution_data.BlockExecutionData{
+		BlockID:             execData.BlockID,
+		ChunkExecutionDatas: chunks,
+	}
+}
+
+func (m *projectionMatcher) isEmpty() bool {
+	return len(m.addresses) == 0 && len(m.contracts) == 0
+}
+
+// chunk returns the projected chunk, or nil if nothing in it was selected.
+func (m *projectionMatcher) chunk(chunk *execution_data.ChunkExecutionData) *execution_data.ChunkExecutionData {
+	projected := &execution_data.ChunkExecutionData{}
+
+	// events emitted by selected transactions are selected too
+	selectedTxs := make(map[flow.Identifier]struct{})
+
+	if chunk.Collection != nil {
+		var transactions []*flow.TransactionBody
+		for _, tx := range chunk.Collection.Transactions {
+			if m.transaction(tx) {
+				transactions = append(transactions, tx)
+				selectedTxs[tx.ID()] = struct{}{}
+			}
+		}
+		if len(transactions) > 0 {
+			projected.Collection = &flow.Collection{Transactions: transactions}
+		}
+	}
+
+	for _, event := range chunk.Events {
+		_, selected := selectedTxs[event.TransactionID]
+		if selected || m.events.matches(event.Type) {
+			projected.Events = append(projected.Events, event)
+		}
+	}
+
+	if chunk.TrieUpdate != nil {
+		trieUpdate := &ledger.TrieUpdate{RootHash: chunk.TrieUpdate.RootHash}
+		for i, payload := range chunk.TrieUpdate.Payloads {
+			if m.payload(payload) {
+				trieUpdate.Paths = append(trieUpdate.Paths, chunk.TrieUpdate.Paths[i])
+				trieUpdate.Payloads = append(trieUpdate.Payloads, payload)
+			}
+		}
+		if len(trieUpdate.Payloads) > 0 {
+			projected.TrieUpdate = trieUpdate
+		}
+	}
+
+	if projected.Collection == nil && len(projected.Events) == 0 && projected.TrieUpdate == nil {
+		return nil
+	}
+
+	return projected
+}
+
+func (m *projectionMatcher) transaction(tx *flow.TransactionBody) bool {
+	if m.address(tx.Payer) || m.address(tx.ProposalKey.Address) {
+		return true
+	}
+	for _, authorizer := range tx.Authorizers {
+		if m.address(authorizer) {
+			return true
+		}
+	}
+
+	if len(m.contracts) == 0 {
+		return false
+	}
+	for _, match := range contractImport.FindAllSubmatch(tx.Script, -1) {
+		for _, name := range m.contracts[flow.HexToAddress(string(match[2]))] {
+			if string(match[1]) == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (m *projectionMatcher) address(address flow.Address) bool {
+	_, ok := m.addresses[address]
+	return ok
+}
+
+// payload returns true if the register is owned by a selected account, or holds the code of a
+// selected contract.
+func (m *projectionMatcher) payload(payload *ledger.Payload) bool {
+	id, ok := payloadRegisterID(payload)
+	if !ok || len(id.Owner) != flow.AddressLength {
+		return false
+	}
+
+	address := flow.BytesToAddress([]byte(id.Owner))
+	if m.address(address) {
+		return true
+	}
+
+	for _, name := range m.contracts[address] {
+		if id.Key == "code."+name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProjectExecutionData returns a subscription that delivers the responses from sub with the
+// projection applied. Responses with nothing selected are dropped.
+//
+// The projection is validated with Projection.Validate without a chain, so addresses are only
+// checked for format. An error is returned if it fails validation.
+func ProjectExecutionData(
+	ctx context.Context,
+	sub *Subscription[ExecutionDataResponse],
+	projection Projection,
+) (*Subscription[ExecutionDataResponse], error) {
+	if err := projection.Validate(nil); err != nil {
+		return nil, fmt.Errorf("invalid projection: %w", err)
+	}
+	m := projection.matcher()
+
+	projected := NewSubscription[ExecutionDataResponse]()
+
+	go func() {
+		defer close(projected.ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				projected.err = ctx.Err()
+				return
+			case response, ok := <-sub.Channel():
+				if !ok {
+					projected.err = sub.Err()
+					return
+				}
+
+				execData := m.apply(response.ExecutionData)
+				if execData == nil {
+					continue
+				}
+				response.ExecutionData = execData
+
+				select {
+				case <-ctx.Done():
+					projected.err = ctx.Err()
+					return
+				case projected.ch <- response:
+				}
+			}
+		}
+	}()
+
+	return projected, nil
+}
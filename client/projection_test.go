@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onflow/flow-go/ledger"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectionValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		contracts []string
+		err       bool
+	}{
+		{name: "empty"},
+		{name: "valid", contracts: []string{"A.1654653399040a61.FlowToken", "A.0x1654653399040a61.FlowToken"}},
+		{name: "missing name", contracts: []string{"A.1654653399040a61"}, err: true},
+		{name: "event type", contracts: []string{"A.1654653399040a61.FlowToken.TokensDeposited"}, err: true},
+		{name: "bad address", contracts: []string{"A.xyz.FlowToken"}, err: true},
+		{name: "bad name", contracts: []string{"A.1654653399040a61.Flow-Token"}, err: true},
+		{name: "one of several invalid", contracts: []string{"A.1654653399040a61.FlowToken", "FlowToken"}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			projection := Projection{Contracts: tt.contracts}
+
+			_, err := projection.Apply(&execution_data.BlockExecutionData{})
+			assert.Equal(t, tt.err, err != nil, "Apply: %v", err)
+
+			_, err = ProjectExecutionData(ctx, NewSubscription[ExecutionDataResponse](), projection)
+			assert.Equal(t, tt.err, err != nil, "ProjectExecutionData: %v", err)
+
+			err = projection.Validate(nil)
+			assert.Equal(t, tt.err, err != nil, "Validate: %v", err)
+			if err == nil {
+				assert.LessOrEqual(t, len(projection.Contracts), 1, "contracts should be deduplicated")
+			}
+		})
+	}
+}
+
+// testPayload returns a register payload owned by the address, or without an owner if the
+// address is empty.
+func testPayload(owner flow.Address, key string) *ledger.Payload {
+	var parts []ledger.KeyPart
+	if owner != flow.EmptyAddress {
+		parts = append(parts, ledger.NewKeyPart(ledger.KeyPartOwner, owner.Bytes()))
+	}
+	parts = append(parts, ledger.NewKeyPart(ledger.KeyPartKey, []byte(key)))
+	return ledger.NewPayload(ledger.NewKey(parts), []byte("value"))
+}
+
+func TestProjectionApply(t *testing.T) {
+	alice := flow.HexToAddress("01")
+	bob := flow.HexToAddress("02")
+	other := flow.HexToAddress("03")
+	token := flow.HexToAddress("1654653399040a61")
+
+	paidByAlice := &flow.TransactionBody{Script: []byte("transaction {}"), Payer: alice}
+	authorizedByBob := &flow.TransactionBody{
+		Script:      []byte("transaction { prepare(acct: AuthAccount) {} }"),
+		Payer:       other,
+		Authorizers: []flow.Address{bob},
+	}
+	importsToken := &flow.TransactionBody{Script: []byte("import FlowToken from 0x1654653399040a61\ntransaction {}"), Payer: other}
+	importsOther := &flow.TransactionBody{Script: []byte("import FungibleToken from 0x1654653399040a61\ntransaction {}"), Payer: other}
+	unrelated := &flow.TransactionBody{Script: []byte("transaction { execute {} }"), Payer: other}
+
+	fromAliceTx := flow.Event{Type: "A.0000000000000003.Other.Event", TransactionID: paidByAlice.ID()}
+	tokenEvent := flow.Event{Type: "A.1654653399040a61.FlowToken.TokensDeposited", TransactionID: unrelated.ID(), EventIndex: 1}
+	aliceEvent := flow.Event{Type: "A.0000000000000001.Alice.Event", TransactionID: unrelated.ID(), EventIndex: 2}
+	otherEvent := flow.Event{Type: "A.0000000000000003.Other.Event", TransactionID: unrelated.ID(), EventIndex: 3}
+
+	aliceBalance := testPayload(alice, "balance")
+	tokenCode := testPayload(token, "code.FlowToken")
+	otherCode := testPayload(token, "code.FungibleToken")
+	otherBalance := testPayload(other, "balance")
+	noOwner := testPayload(flow.EmptyAddress, "uuid")
+
+	execData := &execution_data.BlockExecutionData{
+		BlockID: flow.Identifier{1},
+		ChunkExecutionDatas: []*execution_data.ChunkExecutionData{
+			{
+				Collection: &flow.Collection{Transactions: []*flow.TransactionBody{
+					paidByAlice, authorizedByBob, importsToken, importsOther, unrelated,
+				}},
+				Events: []flow.Event{fromAliceTx, tokenEvent, aliceEvent, otherEvent},
+				TrieUpdate: &ledger.TrieUpdate{
+					RootHash: ledger.RootHash{1},
+					Paths:    []ledger.Path{{1}, {2}, {3}, {4}, {5}},
+					Payloads: []*ledger.Payload{aliceBalance, tokenCode, otherCode, otherBalance, noOwner},
+				},
+			},
+			{
+				Collection: &flow.Collection{Transactions: []*flow.TransactionBody{unrelated}},
+				Events:     []flow.Event{otherEvent},
+				TrieUpdate: &ledger.TrieUpdate{
+					RootHash: ledger.RootHash{2},
+					Paths:    []ledger.Path{{6}},
+					Payloads: []*ledger.Payload{otherBalance},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		projection Projection
+
+		// expected is the projected first chunk, or nil if nothing is selected. Nothing in the
+		// second chunk is ever selected.
+		expected *execution_data.ChunkExecutionData
+	}{
+		{
+			name:       "payer address",
+			projection: Projection{Addresses: []flow.Address{alice}},
+			expected: &execution_data.ChunkExecutionData{
+				Collection: &flow.Collection{Transactions: []*flow.TransactionBody{paidByAlice}},
+				// events of selected transactions, and events emitted by the account's contracts
+				Events: []flow.Event{fromAliceTx, aliceEvent},
+				TrieUpdate: &ledger.TrieUpdate{
+					RootHash: ledger.RootHash{1},
+					Paths:    []ledger.Path{{1}},
+					Payloads: []*ledger.Payload{aliceBalance},
+				},
+			},
+		},
+		{
+			name:       "authorizer address",
+			projection: Projection{Addresses: []flow.Address{bob}},
+			expected: &execution_data.ChunkExecutionData{
+				Collection: &flow.Collection{Transactions: []*flow.TransactionBody{authorizedByBob}},
+			},
+		},
+		{
+			name:       "contract",
+			projection: Projection{Contracts: []string{"A.1654653399040a61.FlowToken"}},
+			expected: &execution_data.ChunkExecutionData{
+				// only imports of the contract's name from its address match
+				Collection: &flow.Collection{Transactions: []*flow.TransactionBody{importsToken}},
+				Events:     []flow.Event{tokenEvent},
+				TrieUpdate: &ledger.TrieUpdate{
+					RootHash: ledger.RootHash{1},
+					Paths:    []ledger.Path{{2}},
+					Payloads: []*ledger.Payload{tokenCode},
+				},
+			},
+		},
+		{
+			name:       "nothing selected",
+			projection: Projection{Addresses: []flow.Address{flow.HexToAddress("04")}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected, err := tt.projection.Apply(execData)
+			require.NoError(t, err)
+
+			if tt.expected == nil {
+				assert.Nil(t, projected)
+				return
+			}
+
+			require.NotNil(t, projected)
+			assert.Equal(t, execData.BlockID, projected.BlockID)
+			require.Len(t, projected.ChunkExecutionDatas, 1, "chunks with nothing selected are removed")
+			assert.Equal(t, tt.expected, projected.ChunkExecutionDatas[0])
+		})
+	}
+
+	t.Run("empty projection", func(t *testing.T) {
+		projected, err := Projection{}.Apply(execData)
+		require.NoError(t, err)
+		assert.Same(t, execData, projected)
+	})
+}
@@ -2,11 +2,15 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 
@@ -26,12 +30,29 @@ type rawEventsResponse struct {
 }
 
 type RestClient struct {
-	address string
+	address   string
+	log       Logger
+	metrics   Metrics
+	guard     GuardConfig
+	userAgent string
+	tlsConfig *tls.Config
+	auth      *authCredentials
 }
 
-func NewRestClient(address string) (*RestClient, error) {
+func NewRestClient(address string, opts ...Option) (*RestClient, error) {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	return &RestClient{
-		address: address,
+		address:   address,
+		log:       cfg.logger,
+		metrics:   cfg.metrics,
+		guard:     cfg.guard,
+		userAgent: cfg.userAgent,
+		tlsConfig: cfg.tlsConfig,
+		auth:      cfg.authCredentials(),
 	}, nil
 }
 
@@ -45,8 +66,18 @@ func (c *RestClient) SubscribeEvents(
 		return nil, fmt.Errorf("cannot specify both start block ID and start height")
 	}
 
+	// the chain is not known to the REST client, so addresses are only checked for format
+	if err := filter.Validate(nil); err != nil {
+		return nil, fmt.Errorf("invalid event filter: %w", err)
+	}
+
+	scheme := "ws"
+	if c.tlsConfig != nil {
+		scheme = "wss"
+	}
+
 	url := url.URL{
-		Scheme: "ws",
+		Scheme: scheme,
 		Host:   c.address,
 		Path:   "/v1/subscribe_events",
 	}
@@ -71,12 +102,34 @@ func (c *RestClient) SubscribeEvents(
 
 	url.RawQuery = query.Encode()
 
-	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url.String(), nil)
+	header := http.Header{}
+	header.Set("User-Agent", c.userAgent)
+
+	if c.auth != nil {
+		if c.auth.requireTLS && c.tlsConfig == nil {
+			return nil, fmt.Errorf("credentials require TLS, use WithTLS or WithInsecureAuth")
+		}
+		if err := c.auth.httpHeader(ctx, header); err != nil {
+			return nil, err
+		}
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = c.tlsConfig
+
+	conn, _, err := dialer.DialContext(ctx, url.String(), header)
 	if err != nil {
+		c.metrics.Error("subscribe_events", err)
 		return nil, err
 	}
 
-	sub := NewSubscription[EventsResponse]()
+	sub := newStreamSubscription[EventsResponse](streamRestEvents, c.metrics)
+	guard := &subscriptionGuard[EventsResponse]{
+		config:    c.guard,
+		allowGaps: true,
+		log:       c.log,
+	}
+
 	go func() {
 		defer close(sub.ch)
 		defer conn.Close()
@@ -90,23 +143,53 @@ func (c *RestClient) SubscribeEvents(
 			default:
 			}
 
-			var resp *rawEventsResponse
-			err := conn.ReadJSON(&resp)
+			_, message, err := conn.ReadMessage()
 			if err == io.EOF {
 				return
 			}
 			if err != nil {
+				c.metrics.Error("subscribe_events", err)
 				sub.err = fmt.Errorf("error receiving execution data: %w", err)
 				return
 			}
 
+			start := time.Now()
+			var resp *rawEventsResponse
+			if err := json.Unmarshal(message, &resp); err != nil {
+				c.metrics.Error("convert_events", err)
+				sub.err = fmt.Errorf("error decoding events response: %w", err)
+				return
+			}
+
 			eventsResponse, err := convertEventResponse(resp)
 			if err != nil {
+				c.metrics.Error("convert_events", err)
 				sub.err = err
 				return
 			}
+			c.metrics.ConversionDuration(streamRestEvents, time.Since(start))
+			c.metrics.BlockReceived(streamRestEvents, len(eventsResponse.Events), len(message))
+
+			c.log.Debug("received events",
+				F("subscription_id", sub.ID()),
+				F("height", eventsResponse.Height),
+				F("block_id", eventsResponse.BlockID),
+				F("events", len(eventsResponse.Events)),
+			)
 
-			sub.ch <- *eventsResponse
+			responses, err := guard.check(ctx, *eventsResponse)
+			if err != nil {
+				sub.err = err
+				return
+			}
+
+			for _, response := range responses {
+				if err := sub.send(ctx, response); err != nil {
+					sub.err = err
+					return
+				}
+				c.metrics.DeliveredHeight(streamRestEvents, response.Height)
+			}
 		}
 	}()
 
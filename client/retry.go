@@ -0,0 +1,299 @@
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+	defaultRetryJitter         = 0.2
+	defaultRetryBudgetRatio    = 0.1
+	defaultRetryBudgetBurst    = 10
+
+	retryAfterHeader = "retry-after"
+)
+
+var defaultRetryableCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.Aborted}
+
+// RateLimitConfig configures a token bucket limiting the rate of requests made to the access
+// node. Streams are not limited.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained rate of requests.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that may be made at once. Defaults to RequestsPerSecond,
+	// or 1 if it's lower.
+	Burst int
+}
+
+// RetryConfig configures retries of failed requests made to the access node. Streams are not
+// retried. If the access node returns a retry delay, either as RetryInfo in the error details
+// or in a retry-after header, it is used if longer than the backoff.
+type RetryConfig struct {
+	// MaxAttempts is the maximum number of attempts for each request, including the first.
+	// Defaults to 3.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. The delay doubles for each retry.
+	// Defaults to 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between attempts. Defaults to 10s.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of the delay randomly added or removed, between 0 and 1.
+	// Defaults to 0.2.
+	Jitter float64
+
+	// RetryableCodes are the grpc codes that are retried. Defaults to Unavailable,
+	// ResourceExhausted and Aborted.
+	RetryableCodes []codes.Code
+
+	// BudgetRatio is the maximum ratio of retries to requests across all requests made by the
+	// client, so that retries don't multiply the load on an overloaded access node.
+	// Defaults to 0.1.
+	BudgetRatio float64
+
+	// BudgetBurst is the number of retries allowed beyond the budget ratio. Defaults to 10.
+	BudgetBurst int
+}
+
+// tokenBucket is a token bucket that refills continuously at a fixed rate.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available, or the context is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		delay := b.take()
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take takes a token if one is available, otherwise it returns the time until one will be.
+func (b *tokenBucket) take() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func rateLimitUnaryInterceptor(config RateLimitConfig) grpc.UnaryClientInterceptor {
+	if config.Burst <= 0 {
+		config.Burst = int(math.Max(1, config.RequestsPerSecond))
+	}
+	bucket := newTokenBucket(config.RequestsPerSecond, config.Burst)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if err := bucket.wait(ctx); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// retryBudget limits retries to a ratio of requests. Each request deposits the ratio, and each
+// retry withdraws one.
+type retryBudget struct {
+	mu      sync.Mutex
+	ratio   float64
+	burst   float64
+	balance float64
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.balance = math.Min(b.burst, b.balance+b.ratio)
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.balance < 1 {
+		return false
+	}
+	b.balance--
+	return true
+}
+
+type retrier struct {
+	config RetryConfig
+	budget *retryBudget
+	log    Logger
+}
+
+func newRetrier(config RetryConfig, log Logger) *retrier {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if config.Jitter <= 0 {
+		config.Jitter = defaultRetryJitter
+	}
+	if len(config.RetryableCodes) == 0 {
+		config.RetryableCodes = defaultRetryableCodes
+	}
+	if config.BudgetRatio <= 0 {
+		config.BudgetRatio = defaultRetryBudgetRatio
+	}
+	if config.BudgetBurst <= 0 {
+		config.BudgetBurst = defaultRetryBudgetBurst
+	}
+
+	return &retrier{
+		config: config,
+		budget: &retryBudget{
+			ratio:   config.BudgetRatio,
+			burst:   float64(config.BudgetBurst),
+			balance: float64(config.BudgetBurst),
+		},
+		log: log,
+	}
+}
+
+func (r *retrier) retryable(err error) bool {
+	code := status.Code(err)
+	for _, c := range r.config.RetryableCodes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry, starting from 1.
+func (r *retrier) backoff(retry int) time.Duration {
+	delay := float64(r.config.InitialBackoff) * math.Pow(2, float64(retry-1))
+	delay = math.Min(delay, float64(r.config.MaxBackoff))
+	delay += delay * r.config.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(delay)
+}
+
+func (r *retrier) unaryInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		r.budget.deposit()
+
+		for attempt := 1; ; attempt++ {
+			var header, trailer metadata.MD
+			err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+			if err == nil || !r.retryable(err) || attempt >= r.config.MaxAttempts {
+				return err
+			}
+			if !r.budget.withdraw() {
+				r.log.Debug("retry budget exhausted", F("method", method), F("error", err))
+				return err
+			}
+
+			delay := r.backoff(attempt)
+			if hint, ok := retryAfter(err, metadata.Join(header, trailer)); ok && hint > delay {
+				delay = hint
+			}
+
+			// don't wait if the request can't be retried before the deadline
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+				return err
+			}
+
+			r.log.Debug("retrying request",
+				F("method", method),
+				F("attempt", attempt),
+				F("delay", delay),
+				F("error", err),
+			)
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// retryAfter returns the retry delay requested by the access node, from RetryInfo error details
+// or a retry-after header in seconds.
+func retryAfter(err error, header metadata.MD) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration(), true
+		}
+	}
+
+	if values := header.Get(retryAfterHeader); len(values) > 0 {
+		if seconds, err := strconv.Atoi(values[0]); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	return 0, false
+}
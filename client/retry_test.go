@@ -0,0 +1,308 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow/protobuf/go/flow/executiondata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// retryServer is an in-process execution data API server that fails GetExecutionDataByBlockID
+// with each of its errors in turn, then succeeds. It records the time of each request.
+type retryServer struct {
+	executiondata.UnimplementedExecutionDataAPIServer
+
+	listener *bufconn.Listener
+
+	// header is sent with each failure
+	header metadata.MD
+
+	mu    sync.Mutex
+	errs  []error
+	calls []time.Time
+}
+
+func newRetryServer(t *testing.T, errs ...error) *retryServer {
+	s := &retryServer{
+		listener: bufconn.Listen(1 << 20),
+		errs:     errs,
+	}
+
+	server := grpc.NewServer()
+	executiondata.RegisterExecutionDataAPIServer(server, s)
+
+	go func() {
+		_ = server.Serve(s.listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return s
+}
+
+func (s *retryServer) GetExecutionDataByBlockID(
+	ctx context.Context,
+	_ *executiondata.GetExecutionDataByBlockIDRequest,
+) (*executiondata.GetExecutionDataByBlockIDResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, time.Now())
+	if len(s.errs) == 0 {
+		return &executiondata.GetExecutionDataByBlockIDResponse{}, nil
+	}
+
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	if s.header != nil {
+		_ = grpc.SetHeader(ctx, s.header)
+	}
+	return nil, err
+}
+
+// client returns a client for the server retrying requests with the config.
+func (s *retryServer) client(t *testing.T, config RetryConfig) executiondata.ExecutionDataAPIClient {
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(newRetrier(config, NoopLogger{}).unaryInterceptor()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return executiondata.NewExecutionDataAPIClient(conn)
+}
+
+// intervals returns the time between each request and the one before it.
+func (s *retryServer) intervals() []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var intervals []time.Duration
+	for i := 1; i < len(s.calls); i++ {
+		intervals = append(intervals, s.calls[i].Sub(s.calls[i-1]))
+	}
+	return intervals
+}
+
+// retryInfoError returns an error with the code and a RetryInfo detail with the delay.
+func retryInfoError(t *testing.T, code codes.Code, delay time.Duration) error {
+	st, err := status.New(code, "retry later").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	require.NoError(t, err)
+	return st.Err()
+}
+
+func TestTokenBucket(t *testing.T) {
+	t.Run("burst then rate", func(t *testing.T) {
+		b := newTokenBucket(10, 2)
+
+		assert.Zero(t, b.take())
+		assert.Zero(t, b.take())
+
+		delay := b.take()
+		assert.Greater(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, 100*time.Millisecond)
+	})
+
+	t.Run("refills up to the burst", func(t *testing.T) {
+		b := newTokenBucket(10, 2)
+		b.take()
+		b.take()
+
+		// a tenth of a second refills one token
+		b.last = b.last.Add(-100 * time.Millisecond)
+		assert.Zero(t, b.take())
+		assert.NotZero(t, b.take())
+
+		// a long wait refills no more than the burst
+		b.last = b.last.Add(-time.Minute)
+		assert.Zero(t, b.take())
+		assert.Zero(t, b.take())
+		assert.NotZero(t, b.take())
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		b := newTokenBucket(100, 1)
+		require.NoError(t, b.wait(context.Background()))
+
+		start := time.Now()
+		require.NoError(t, b.wait(context.Background()))
+		assert.GreaterOrEqual(t, time.Since(start), 5*time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, b.wait(ctx), context.Canceled)
+	})
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := &retryBudget{ratio: 0.5, burst: 2}
+	assert.False(t, b.withdraw())
+
+	// two requests earn one retry
+	b.deposit()
+	assert.False(t, b.withdraw())
+	b.deposit()
+	assert.True(t, b.withdraw())
+	assert.False(t, b.withdraw())
+
+	// the balance is capped at the burst
+	for i := 0; i < 10; i++ {
+		b.deposit()
+	}
+	assert.True(t, b.withdraw())
+	assert.True(t, b.withdraw())
+	assert.False(t, b.withdraw())
+}
+
+func TestRetryAfter(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	tests := []struct {
+		name   string
+		err    error
+		header metadata.MD
+		delay  time.Duration
+		ok     bool
+	}{
+		{name: "no hint", err: unavailable},
+		{name: "retry info", err: retryInfoError(t, codes.Unavailable, 2*time.Second), delay: 2 * time.Second, ok: true},
+		{name: "header", err: unavailable, header: metadata.Pairs(retryAfterHeader, "3"), delay: 3 * time.Second, ok: true},
+		{name: "zero header", err: unavailable, header: metadata.Pairs(retryAfterHeader, "0"), ok: true},
+		{name: "header not in seconds", err: unavailable, header: metadata.Pairs(retryAfterHeader, "soon")},
+		{name: "negative header", err: unavailable, header: metadata.Pairs(retryAfterHeader, "-1")},
+		{
+			name:   "retry info preferred",
+			err:    retryInfoError(t, codes.Unavailable, 2*time.Second),
+			header: metadata.Pairs(retryAfterHeader, "3"),
+			delay:  2 * time.Second,
+			ok:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := retryAfter(tt.err, tt.header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.delay, delay)
+		})
+	}
+}
+
+func TestRetryUnaryInterceptor(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	// fast retries without jitter to speak of
+	fast := RetryConfig{InitialBackoff: time.Millisecond, Jitter: 0.01}
+
+	tests := []struct {
+		name   string
+		config RetryConfig
+		errs   []error
+
+		// calls is the number of requests received by the server
+		calls int
+		code  codes.Code
+	}{
+		{
+			name:   "succeeds after retries",
+			config: fast,
+			errs:   []error{unavailable, status.Error(codes.ResourceExhausted, "slow down")},
+			calls:  3,
+			code:   codes.OK,
+		},
+		{
+			name:   "gives up after max attempts",
+			config: RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+			errs:   []error{unavailable, unavailable, unavailable},
+			calls:  2,
+			code:   codes.Unavailable,
+		},
+		{
+			name:   "not retryable",
+			config: fast,
+			errs:   []error{status.Error(codes.PermissionDenied, "denied")},
+			calls:  1,
+			code:   codes.PermissionDenied,
+		},
+		{
+			name: "custom retryable codes",
+			config: RetryConfig{
+				InitialBackoff: time.Millisecond,
+				RetryableCodes: []codes.Code{codes.NotFound},
+			},
+			errs:  []error{status.Error(codes.NotFound, "not indexed"), unavailable},
+			calls: 2,
+			code:  codes.Unavailable,
+		},
+		{
+			name:   "budget exhausted",
+			config: RetryConfig{MaxAttempts: 5, InitialBackoff: time.Millisecond, BudgetBurst: 1},
+			errs:   []error{unavailable, unavailable, unavailable},
+			calls:  2,
+			code:   codes.Unavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRetryServer(t, tt.errs...)
+
+			_, err := s.client(t, tt.config).GetExecutionDataByBlockID(context.Background(), &executiondata.GetExecutionDataByBlockIDRequest{})
+			assert.Equal(t, tt.code, status.Code(err), "error: %v", err)
+			assert.Len(t, s.intervals(), tt.calls-1)
+		})
+	}
+
+	t.Run("backoff doubles", func(t *testing.T) {
+		s := newRetryServer(t, unavailable, unavailable)
+
+		_, err := s.client(t, RetryConfig{InitialBackoff: 20 * time.Millisecond, Jitter: 0.01}).
+			GetExecutionDataByBlockID(context.Background(), &executiondata.GetExecutionDataByBlockIDRequest{})
+		require.NoError(t, err)
+
+		intervals := s.intervals()
+		require.Len(t, intervals, 2)
+		assert.GreaterOrEqual(t, intervals[0], 19*time.Millisecond)
+		assert.GreaterOrEqual(t, intervals[1], 39*time.Millisecond)
+	})
+
+	t.Run("longer retry info delay used", func(t *testing.T) {
+		s := newRetryServer(t, retryInfoError(t, codes.Unavailable, 50*time.Millisecond))
+
+		_, err := s.client(t, fast).GetExecutionDataByBlockID(context.Background(), &executiondata.GetExecutionDataByBlockIDRequest{})
+		require.NoError(t, err)
+
+		intervals := s.intervals()
+		require.Len(t, intervals, 1)
+		assert.GreaterOrEqual(t, intervals[0], 50*time.Millisecond)
+	})
+
+	t.Run("not retried past the deadline", func(t *testing.T) {
+		s := newRetryServer(t, unavailable)
+		s.header = metadata.Pairs(retryAfterHeader, "5")
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		start := time.Now()
+		_, err := s.client(t, fast).GetExecutionDataByBlockID(ctx, &executiondata.GetExecutionDataByBlockIDRequest{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Empty(t, s.intervals())
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
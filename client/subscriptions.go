@@ -1,14 +1,59 @@
 package client
 
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+)
+
+// BlockResponse is implemented by the responses delivered by subscriptions.
+type BlockResponse interface {
+	GetHeight() uint64
+	GetBlockID() flow.Identifier
+}
+
+var nextSubscriptionID atomic.Uint64
+
 type Subscription[T any] struct {
+	id  uint64
 	ch  chan T
 	err error
+
+	stream  string
+	metrics Metrics
 }
 
 func NewSubscription[T any]() *Subscription[T] {
+	return newStreamSubscription[T]("", NoopMetrics{})
+}
+
+func newStreamSubscription[T any](stream string, metrics Metrics) *Subscription[T] {
 	return &Subscription[T]{
-		ch: make(chan T),
+		id:      nextSubscriptionID.Add(1),
+		ch:      make(chan T),
+		stream:  stream,
+		metrics: metrics,
+	}
+}
+
+// ID returns an identifier for the subscription that is unique within the process.
+func (s *Subscription[T]) ID() uint64 {
+	return s.id
+}
+
+// send delivers the value to the consumer, recording the time spent waiting for it to be accepted.
+// It returns the context's error if the context is done before the value is accepted.
+func (s *Subscription[T]) send(ctx context.Context, value T) error {
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case s.ch <- value:
 	}
+	s.metrics.Backpressure(s.stream, time.Since(start))
+	return nil
 }
 
 func (s *Subscription[T]) Channel() <-chan T {
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const tracerName = "github.com/peterargue/execdata-client/client"
+
+// span attribute keys
+const (
+	attrHeight      = attribute.Key("flow.block.height")
+	attrBlockID     = attribute.Key("flow.block.id")
+	attrChunks      = attribute.Key("flow.execution_data.chunks")
+	attrEvents      = attribute.Key("flow.events")
+	attrPayloadSize = attribute.Key("flow.payload.size")
+)
+
+// receiveSpan is embedded in subscription responses to carry the span they were received in.
+type receiveSpan struct {
+	spanContext trace.SpanContext
+}
+
+// TraceContext returns a copy of ctx carrying the span the response was received in. Handlers
+// can use it to start child spans, tracing the block from receipt through processing.
+func (s receiveSpan) TraceContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(ctx, s.spanContext)
+}
+
+// convertExecutionData converts the execution data message within a span, recording the
+// conversion duration.
+func (c *ExecutionDataClient) convertExecutionData(
+	ctx context.Context,
+	stream string,
+	m *entities.BlockExecutionData,
+) (*execution_data.BlockExecutionData, error) {
+	_, span := c.tracer.Start(ctx, "convert.MessageToBlockExecutionData",
+		trace.WithAttributes(
+			attrBlockID.String(convert.MessageToIdentifier(m.GetBlockId()).String()),
+			attrChunks.Int(len(m.GetChunkExecutionData())),
+			attrPayloadSize.Int(proto.Size(m)),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	execData, err := convert.MessageToBlockExecutionData(m, c.chain)
+	if err != nil {
+		recordSpanError(span, err)
+		c.metrics.Error("convert_execution_data", err)
+		return nil, err
+	}
+	c.metrics.ConversionDuration(stream, time.Since(start))
+
+	return execData, nil
+}
+
+// convertLazyExecutionData fully converts the lazy execution data within a span, recording the
+// conversion duration.
+func (c *ExecutionDataClient) convertLazyExecutionData(
+	ctx context.Context,
+	stream string,
+	d *LazyExecutionData,
+) (*execution_data.BlockExecutionData, error) {
+	_, span := c.tracer.Start(ctx, "convert.LazyExecutionData",
+		trace.WithAttributes(
+			attrBlockID.String(d.BlockID().String()),
+			attrChunks.Int(d.NumChunks()),
+			attrPayloadSize.Int(proto.Size(d.Message())),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	execData, err := d.BlockExecutionData()
+	if err != nil {
+		recordSpanError(span, err)
+		c.metrics.Error("convert_execution_data", err)
+		return nil, err
+	}
+	c.metrics.ConversionDuration(stream, time.Since(start))
+
+	return execData, nil
+}
+
+func blockSpanAttributes(height uint64, blockID flow.Identifier) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attrBlockID.String(blockID.String())}
+	if height > 0 {
+		attrs = append(attrs, attrHeight.Int64(int64(height)))
+	}
+	return attrs
+}
+
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, err.Error())
+}
+
+// metadataCarrier adapts grpc metadata to a propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// injectTraceContext adds the trace context from ctx to the outgoing grpc metadata.
+func injectTraceContext(ctx context.Context, propagator propagation.TextMapPropagator) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+
+	propagator.Inject(ctx, metadataCarrier(md))
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func tracingUnaryInterceptor(propagator propagation.TextMapPropagator) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		return invoker(injectTraceContext(ctx, propagator), method, req, reply, cc, opts...)
+	}
+}
+
+func tracingStreamInterceptor(propagator propagation.TextMapPropagator) grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(injectTraceContext(ctx, propagator), desc, cc, method, opts...)
+	}
+}
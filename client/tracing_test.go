@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTracePropagation(t *testing.T) {
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	traceParent := "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01"
+
+	// requests are made with the trace context and existing outgoing metadata
+	newContext := func() (context.Context, context.CancelFunc) {
+		ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "abc")
+		return context.WithTimeout(ctx, 5*time.Second)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected string
+	}{
+		{
+			name:     "propagated",
+			opts:     []Option{WithTracing(trace.NewNoopTracerProvider()), WithPropagator(propagation.TraceContext{})},
+			expected: traceParent,
+		},
+		{
+			name: "not propagated without tracing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAuthServer(t)
+
+			opts := append([]Option{WithDialOptions(s.dialOptions()...)}, tt.opts...)
+			c, err := NewExecutionDataClientWithOptions("bufnet", flow.Emulator.Chain(), opts...)
+			require.NoError(t, err)
+			defer c.Close()
+
+			check := func(md metadata.MD) {
+				t.Helper()
+				if tt.expected == "" {
+					assert.Empty(t, md.Get("traceparent"))
+				} else {
+					assert.Equal(t, []string{tt.expected}, md.Get("traceparent"))
+				}
+				assert.Equal(t, []string{"abc"}, md.Get("x-request-id"))
+			}
+
+			t.Run("unary", func(t *testing.T) {
+				ctx, cancel := newContext()
+				defer cancel()
+
+				_, err := c.GetExecutionDataForBlockID(ctx, flow.Identifier{1})
+				require.Error(t, err, "the server doesn't implement the API")
+
+				check(<-s.requests)
+			})
+
+			t.Run("stream", func(t *testing.T) {
+				ctx, cancel := newContext()
+				defer cancel()
+
+				sub, err := c.SubscribeEvents(ctx, flow.ZeroID, 1, EventFilter{})
+				require.NoError(t, err)
+
+				check(<-s.requests)
+
+				for range sub.Channel() {
+				}
+				assert.Error(t, sub.Err(), "the server doesn't implement the API")
+			})
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
 	"github.com/onflow/flow/protobuf/go/flow/access"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -24,3 +25,12 @@ func GetChain(ctx context.Context, accessURL string) (flow.Chain, error) {
 	}
 	return flow.ChainID(resp.ChainId).Chain(), nil
 }
+
+// countEvents returns the total number of events in the execution data.
+func countEvents(execData *execution_data.BlockExecutionData) int {
+	count := 0
+	for _, chunk := range execData.ChunkExecutionDatas {
+		count += len(chunk.Events)
+	}
+	return count
+}
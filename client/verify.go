@@ -0,0 +1,335 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/onflow/flow-go/engine/common/rpc/convert"
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/blobs"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+	"github.com/onflow/flow/protobuf/go/flow/access"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"google.golang.org/grpc"
+)
+
+// getSealedExecutionResult returns the sealed execution result for the given block.
+func (c *ExecutionDataClient) getSealedExecutionResult(
+	ctx context.Context,
+	blockID flow.Identifier,
+	opts ...grpc.CallOption,
+) (*entities.ExecutionResult, error) {
+	resp, err := c.accessClient.GetExecutionResultForBlockID(ctx, &access.GetExecutionResultForBlockIDRequest{
+		BlockId: blockID[:],
+	}, opts...)
+	if err != nil {
+		c.metrics.Error("get_execution_result", err)
+		return nil, fmt.Errorf("could not get execution result for block %s: %w", blockID, err)
+	}
+
+	return resp.GetExecutionResult(), nil
+}
+
+// VerifyExecutionData checks the execution data against the block's sealed execution result.
+//
+// The execution data's IDs are recomputed using flow-go's default serializer, which derives the
+// CIDs of each chunk's execution data and of the root blob that references them. Each chunk's ID
+// is compared to the ExecutionDataId of the result's chunk, if the access node provides it, and
+// the root ID to the ExecutionDataID committed to in the result. Event payloads must be in their
+// original CCF encoding, which the client requests when verification is enabled with
+// WithExecutionDataVerification.
+//
+// A *VerificationError naming the chunk or root is returned if a computed ID does not match.
+func (c *ExecutionDataClient) VerifyExecutionData(
+	ctx context.Context,
+	execData *execution_data.BlockExecutionData,
+	opts ...grpc.CallOption,
+) error {
+	result, err := c.getSealedExecutionResult(ctx, execData.BlockID, opts...)
+	if err != nil {
+		return err
+	}
+
+	if len(result.GetChunks()) != len(execData.ChunkExecutionDatas) {
+		return fmt.Errorf("execution data for block %s has %d chunks, sealed execution result has %d",
+			execData.BlockID, len(execData.ChunkExecutionDatas), len(result.GetChunks()))
+	}
+
+	computed, chunkIDs, err := calculateExecutionDataIDs(ctx, execData)
+	if err != nil {
+		return fmt.Errorf("could not calculate execution data IDs for block %s: %w", execData.BlockID, err)
+	}
+
+	if len(chunkIDs) != len(result.GetChunks()) {
+		return fmt.Errorf("execution data root for block %s references %d chunks, sealed execution result has %d",
+			execData.BlockID, len(chunkIDs), len(result.GetChunks()))
+	}
+
+	for i, chunk := range result.GetChunks() {
+		// older access nodes don't populate the chunk's execution data ID
+		if len(chunk.GetExecutionDataId()) == 0 {
+			continue
+		}
+
+		expected := convert.MessageToIdentifier(chunk.GetExecutionDataId())
+		if chunkIDs[i] != expected {
+			return &VerificationError{
+				BlockID:  execData.BlockID,
+				Expected: expected,
+				Computed: chunkIDs[i],
+				Field:    fmt.Sprintf("chunk %d execution data ID", i),
+			}
+		}
+	}
+
+	// the field is marked deprecated in the protobuf definition, but is still populated by access nodes
+	expected := convert.MessageToIdentifier(result.GetExecutionDataId())
+	if computed != expected {
+		return &VerificationError{
+			BlockID:  execData.BlockID,
+			Expected: expected,
+			Computed: computed,
+			Field:    "execution data ID",
+		}
+	}
+
+	return nil
+}
+
+// calculateExecutionDataIDs returns the root ID of the execution data and the ID of each chunk's
+// execution data. The blobs are stored in memory so the chunk IDs can be read back from the root.
+func calculateExecutionDataIDs(
+	ctx context.Context,
+	execData *execution_data.BlockExecutionData,
+) (flow.Identifier, []flow.Identifier, error) {
+	blobstore := blobs.NewBlobstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	store := execution_data.NewExecutionDataStore(blobstore, execution_data.DefaultSerializer)
+
+	rootID, err := store.Add(ctx, execData)
+	if err != nil {
+		return flow.ZeroID, nil, err
+	}
+
+	blob, err := blobstore.Get(ctx, flow.IdToCid(rootID))
+	if err != nil {
+		return flow.ZeroID, nil, fmt.Errorf("could not get root blob: %w", err)
+	}
+	decoded, err := execution_data.DefaultSerializer.Deserialize(bytes.NewReader(blob.RawData()))
+	if err != nil {
+		return flow.ZeroID, nil, fmt.Errorf("could not decode root blob: %w", err)
+	}
+	root, ok := decoded.(*execution_data.BlockExecutionDataRoot)
+	if !ok {
+		return flow.ZeroID, nil, fmt.Errorf("unexpected root blob type %T", decoded)
+	}
+
+	chunkIDs := make([]flow.Identifier, len(root.ChunkExecutionDataIDs))
+	for i, c := range root.ChunkExecutionDataIDs {
+		chunkIDs[i], err = flow.CidToId(c)
+		if err != nil {
+			return flow.ZeroID, nil, fmt.Errorf("could not convert chunk %d execution data ID: %w", i, err)
+		}
+	}
+
+	return rootID, chunkIDs, nil
+}
+
+// eventEncoding returns the event encoding to request from the access node. Verification
+// requires the original CCF encoded payloads, otherwise the node's default is used.
+func (c *ExecutionDataClient) eventEncoding() entities.EventEncodingVersion {
+	if c.verifyExecutionData || c.verifyEvents {
+		return entities.EventEncodingVersion_CCF_V0
+	}
+	return entities.EventEncodingVersion_JSON_CDC_V0
+}
+
+// VerifyEvents checks that the events in the response are complete and match the event
+// collection hashes in the block's sealed execution result.
+//
+// If the subscription used an empty filter, the response contains all of the block's events,
+// which are grouped by chunk and hashed directly. Otherwise, the block's full execution data is
+// fetched and verified, and the response is checked against the events in it that match the
+// filter. Event payloads must be in their original CCF encoding, which the client requests
+// when verification is enabled with WithEventVerification.
+//
+// VerifyEvents only checks the response it's given. Filtered subscriptions skip blocks with no
+// matching events, so a block withheld by the access node can only be detected by checking the
+// skipped heights with VerifyNoEvents, which subscriptions do when verification is enabled.
+//
+// A *VerificationError is returned if the events do not match.
+func (c *ExecutionDataClient) VerifyEvents(
+	ctx context.Context,
+	response EventsResponse,
+	filter EventFilter,
+	opts ...grpc.CallOption,
+) error {
+	result, err := c.getSealedExecutionResult(ctx, response.BlockID, opts...)
+	if err != nil {
+		return err
+	}
+
+	if filter.isEmpty() {
+		chunkEvents, err := groupEventsByChunk(result, response.Events)
+		if err != nil {
+			return fmt.Errorf("could not group events for block %s: %w", response.BlockID, err)
+		}
+		return verifyChunkEvents(response.BlockID, result, chunkEvents)
+	}
+
+	execData, err := c.executionData().GetExecutionDataForBlockID(ctx, response.BlockID, opts...)
+	if err != nil {
+		return fmt.Errorf("could not get execution data to verify events: %w", err)
+	}
+
+	chunkEvents := make([]flow.EventsList, 0, len(execData.ChunkExecutionDatas))
+	var expected flow.EventsList
+	for _, chunk := range execData.ChunkExecutionDatas {
+		chunkEvents = append(chunkEvents, chunk.Events)
+		for _, event := range chunk.Events {
+			if filter.matches(event.Type) {
+				expected = append(expected, event)
+			}
+		}
+	}
+
+	if err := verifyChunkEvents(response.BlockID, result, chunkEvents); err != nil {
+		return err
+	}
+
+	expectedHash, err := flow.EventsMerkleRootHash(expected)
+	if err != nil {
+		return fmt.Errorf("could not hash expected events: %w", err)
+	}
+	receivedHash, err := flow.EventsMerkleRootHash(response.Events)
+	if err != nil {
+		return fmt.Errorf("could not hash received events: %w", err)
+	}
+
+	if expectedHash != receivedHash {
+		return &VerificationError{
+			BlockID:  response.BlockID,
+			Expected: expectedHash,
+			Computed: receivedHash,
+			Field:    fmt.Sprintf("filtered events (expected %d, received %d)", len(expected), len(response.Events)),
+		}
+	}
+
+	return nil
+}
+
+// VerifyNoEvents checks that none of the sealed blocks between startHeight and endHeight
+// (inclusive) contain events matching the filter. Each block's execution data is fetched and
+// verified against its sealed execution result, so this is expensive for large ranges.
+//
+// A *VerificationError is returned if a block contains matching events.
+func (c *ExecutionDataClient) VerifyNoEvents(
+	ctx context.Context,
+	startHeight uint64,
+	endHeight uint64,
+	filter EventFilter,
+	opts ...grpc.CallOption,
+) error {
+	for height := startHeight; height <= endHeight; height++ {
+		execData, err := c.executionData().GetExecutionDataByHeight(ctx, height, opts...)
+		if err != nil {
+			return fmt.Errorf("could not get execution data to verify height %d: %w", height, err)
+		}
+
+		// execution data is already verified when it's fetched if verification is enabled
+		if !c.verifyExecutionData {
+			if err := c.VerifyExecutionData(ctx, execData, opts...); err != nil {
+				return err
+			}
+		}
+
+		var matching flow.EventsList
+		for _, chunk := range execData.ChunkExecutionDatas {
+			for _, event := range chunk.Events {
+				if filter.matches(event.Type) {
+					matching = append(matching, event)
+				}
+			}
+		}
+
+		if len(matching) > 0 {
+			expected, err := flow.EventsMerkleRootHash(matching)
+			if err != nil {
+				return fmt.Errorf("could not hash withheld events: %w", err)
+			}
+			computed, err := flow.EventsMerkleRootHash(nil)
+			if err != nil {
+				return fmt.Errorf("could not hash received events: %w", err)
+			}
+			return &VerificationError{
+				BlockID:  execData.BlockID,
+				Expected: expected,
+				Computed: computed,
+				Field:    fmt.Sprintf("events at skipped height %d (%d matching events withheld)", height, len(matching)),
+			}
+		}
+
+		if height == endHeight {
+			// avoid overflow when endHeight is the max uint64
+			break
+		}
+	}
+
+	return nil
+}
+
+// groupEventsByChunk splits a block's events into chunks using the number of transactions in
+// each of the result's chunks. Events must be in transaction order.
+func groupEventsByChunk(result *entities.ExecutionResult, events []flow.Event) ([]flow.EventsList, error) {
+	chunks := result.GetChunks()
+	chunkEvents := make([]flow.EventsList, len(chunks))
+
+	chunkIndex := 0
+	chunkEnd := uint32(0)
+	if len(chunks) > 0 {
+		chunkEnd = chunks[0].GetNumberOfTransactions()
+	}
+
+	for _, event := range events {
+		for event.TransactionIndex >= chunkEnd {
+			chunkIndex++
+			if chunkIndex >= len(chunks) {
+				return nil, fmt.Errorf("event for transaction %d is beyond the last chunk", event.TransactionIndex)
+			}
+			chunkEnd += chunks[chunkIndex].GetNumberOfTransactions()
+		}
+		chunkEvents[chunkIndex] = append(chunkEvents[chunkIndex], event)
+	}
+
+	return chunkEvents, nil
+}
+
+// verifyChunkEvents checks the hash of each chunk's events against the result's event collection.
+func verifyChunkEvents(blockID flow.Identifier, result *entities.ExecutionResult, chunkEvents []flow.EventsList) error {
+	chunks := result.GetChunks()
+	if len(chunks) != len(chunkEvents) {
+		return fmt.Errorf("block %s has events for %d chunks, sealed execution result has %d",
+			blockID, len(chunkEvents), len(chunks))
+	}
+
+	for i, events := range chunkEvents {
+		computed, err := flow.EventsMerkleRootHash(events)
+		if err != nil {
+			return fmt.Errorf("could not hash events for chunk %d: %w", i, err)
+		}
+
+		expected := convert.MessageToIdentifier(chunks[i].GetEventCollection())
+		if computed != expected {
+			return &VerificationError{
+				BlockID:  blockID,
+				Expected: expected,
+				Computed: computed,
+				Field:    fmt.Sprintf("chunk %d event collection hash", i),
+			}
+		}
+	}
+
+	return nil
+}
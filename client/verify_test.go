@@ -0,0 +1,95 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow/protobuf/go/flow/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupEventsByChunk(t *testing.T) {
+	// result returns an execution result with a chunk for each transaction count
+	result := func(transactions ...uint32) *entities.ExecutionResult {
+		r := &entities.ExecutionResult{}
+		for _, n := range transactions {
+			r.Chunks = append(r.Chunks, &entities.Chunk{NumberOfTransactions: n})
+		}
+		return r
+	}
+
+	// events returns an event for each transaction index
+	events := func(txIndexes ...uint32) []flow.Event {
+		var events []flow.Event
+		for i, txIndex := range txIndexes {
+			events = append(events, flow.Event{TransactionIndex: txIndex, EventIndex: uint32(i)})
+		}
+		return events
+	}
+
+	tests := []struct {
+		name     string
+		result   *entities.ExecutionResult
+		events   []flow.Event
+		expected [][]uint32
+		err      bool
+	}{
+		{
+			name:     "no events",
+			result:   result(2, 1),
+			events:   nil,
+			expected: [][]uint32{nil, nil},
+		},
+		{
+			name:     "events in each chunk",
+			result:   result(2, 1),
+			events:   events(0, 0, 1, 2),
+			expected: [][]uint32{{0, 0, 1}, {2}},
+		},
+		{
+			name:     "events only in last chunk",
+			result:   result(2, 3),
+			events:   events(4),
+			expected: [][]uint32{nil, {4}},
+		},
+		{
+			name:     "chunk without transactions",
+			result:   result(1, 0, 1),
+			events:   events(0, 1),
+			expected: [][]uint32{{0}, nil, {1}},
+		},
+		{
+			name:   "event beyond last chunk",
+			result: result(1, 1),
+			events: events(0, 2),
+			err:    true,
+		},
+		{
+			name:   "no chunks",
+			result: result(),
+			events: events(0),
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunkEvents, err := groupEventsByChunk(tt.result, tt.events)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			require.Len(t, chunkEvents, len(tt.expected))
+			for i, expected := range tt.expected {
+				var actual []uint32
+				for _, event := range chunkEvents[i] {
+					actual = append(actual, event.TransactionIndex)
+				}
+				assert.Equal(t, expected, actual, "chunk %d", i)
+			}
+		})
+	}
+}
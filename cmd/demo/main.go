@@ -22,12 +22,14 @@ func main() {
 	var accessURL,
 		filterEvents,
 		filterContracts,
-		filterAddresses string
+		filterAddresses,
+		where string
 
 	flag.StringVar(&accessURL, "host", "access-001.devnet49.nodes.onflow.org:9000", "execution data api url.")
 	flag.StringVar(&filterEvents, "events", "", "comma separated list of events to filter for.")
 	flag.StringVar(&filterContracts, "contracts", "", "comma separated list of contracts to filter events by.")
 	flag.StringVar(&filterAddresses, "addresses", "", "comma separated list of addresses to filter events by.")
+	flag.StringVar(&where, "where", "", "expression to filter events by client-side, e.g. 'type == \"A.*.FlowToken.TokensDeposited\" and amount > 1000'.")
 	flag.Parse()
 
 	filter := defaultFilter(accessURL)
@@ -35,6 +37,15 @@ func main() {
 		filter = buildFilter(filterEvents, filterContracts, filterAddresses)
 	}
 
+	var expr *client.EventExpression
+	if where != "" {
+		var err error
+		expr, err = client.ParseEventExpression(where)
+		if err != nil {
+			log.Fatalf("invalid where expression: %v", err)
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -43,19 +54,34 @@ func main() {
 		log.Fatalf("could not get chain: %v", err)
 	}
 
+	if err := filter.Validate(chain); err != nil {
+		log.Fatalf("invalid filter: %v", err)
+	}
+
 	execClient, err := client.NewExecutionDataClient(accessURL, chain)
 	if err != nil {
 		log.Fatalf("could not create execution data client: %v", err)
 	}
 
-	err = followBlocks(ctx, execClient, filter)
+	err = followBlocks(ctx, execClient, filter, expr)
 	if err != nil {
 		log.Fatalf("could not follow blocks: %v", err)
 	}
 }
 
-func followBlocks(ctx context.Context, execClient *client.ExecutionDataClient, filter client.EventFilter) error {
-	sub, err := execClient.SubscribeEvents(ctx, flow.ZeroID, 0, filter)
+func followBlocks(
+	ctx context.Context,
+	execClient *client.ExecutionDataClient,
+	filter client.EventFilter,
+	expr *client.EventExpression,
+) error {
+	var sub *client.Subscription[client.EventsResponse]
+	var err error
+	if expr != nil {
+		sub, err = execClient.SubscribeEventsWhere(ctx, flow.ZeroID, 0, filter, expr)
+	} else {
+		sub, err = execClient.SubscribeEvents(ctx, flow.ZeroID, 0, filter)
+	}
 	if err != nil {
 		return fmt.Errorf("could not subscribe to execution data: %w", err)
 	}
@@ -83,19 +109,22 @@ func followBlocks(ctx context.Context, execClient *client.ExecutionDataClient, f
 func buildFilter(events, contracts, addresses string) client.EventFilter {
 	filter := client.EventFilter{}
 
-	if events != "" {
-		filter.EventTypes = strings.Split(events, ",")
-	}
+	filter.EventTypes = splitList(events)
+	filter.Contracts = splitList(contracts)
+	filter.Addresses = splitList(addresses)
 
-	if contracts != "" {
-		filter.Contracts = strings.Split(contracts, ",")
-	}
+	return filter
+}
 
-	if addresses != "" {
-		filter.Addresses = strings.Split(addresses, ",")
+// splitList splits a comma separated list, ignoring whitespace and empty entries.
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
 	}
-
-	return filter
+	return values
 }
 
 func defaultFilter(accessURL string) client.EventFilter {
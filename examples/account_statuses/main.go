@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/onflow/flow-go/model/flow"
+
+	"github.com/peterargue/execdata-client/client"
+)
+
+// This app demonstrates how to use the Execution Data API to stream account statuses.
+// It follows the activity of the testnet FlowToken account, including its modified registers.
+
+const (
+	accessURL = "access-001.devnet49.nodes.onflow.org:9000"
+)
+
+func main() {
+	ctx := context.Background()
+
+	chain, err := client.GetChain(ctx, accessURL)
+	if err != nil {
+		log.Fatalf("could not get chain: %v", err)
+	}
+
+	execClient, err := client.NewExecutionDataClient(accessURL, chain)
+	if err != nil {
+		log.Fatalf("could not create execution data client: %v", err)
+	}
+
+	addresses := []flow.Address{flow.HexToAddress("7e60df042a9c0868")}
+	sub, err := execClient.SubscribeAccountStatuses(ctx, flow.ZeroID, 0, addresses, client.AccountStatusConfig{
+		IncludeRegisters: true,
+	})
+	if err != nil {
+		log.Fatalf("could not subscribe to account statuses: %v", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case response, ok := <-sub.Channel():
+			if sub.Err() != nil {
+				log.Fatalf("error in subscription: %v", sub.Err())
+			}
+			if !ok {
+				log.Fatalf("subscription closed")
+			}
+
+			log.Printf("block %d %s:", response.Height, response.BlockID)
+			for _, account := range response.Accounts {
+				log.Printf("  %s: %d events, %d registers", account.Address, len(account.Events), len(account.Registers))
+				for _, event := range account.Events {
+					log.Printf("    %s", event.Type)
+				}
+			}
+		}
+	}
+}
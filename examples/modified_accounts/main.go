@@ -4,21 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/onflow/flow/protobuf/go/flow/access"
-	"github.com/onflow/flow/protobuf/go/flow/entities"
-	executiondata "github.com/onflow/flow/protobuf/go/flow/executiondata"
-
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/status"
 
-	"github.com/onflow/flow-go/engine/common/rpc/convert"
-	"github.com/onflow/flow-go/ledger"
 	"github.com/onflow/flow-go/model/flow"
+	"github.com/onflow/flow-go/module/executiondatasync/execution_data"
+
+	"github.com/peterargue/execdata-client/client"
 )
 
 // This app demonstrates how to use the Execution Data API to poll for BlockExecutionData.
@@ -30,9 +25,7 @@ const (
 
 type Tracker struct {
 	accessClient access.AccessAPIClient
-	execClient   executiondata.ExecutionDataAPIClient
-
-	chain flow.Chain
+	execClient   *client.ExecutionDataClient
 }
 
 func main() {
@@ -43,17 +36,20 @@ func main() {
 		log.Fatalf("could not connect to access api server: %v", err)
 	}
 
-	t := &Tracker{
-		accessClient: access.NewAccessAPIClient(conn),
-		execClient:   executiondata.NewExecutionDataAPIClient(conn),
+	chain, err := client.GetChain(ctx, accessURL)
+	if err != nil {
+		log.Fatalf("could not get chain: %v", err)
 	}
 
-	// get the network's chainID
-	resp, err := t.accessClient.GetNetworkParameters(ctx, &access.GetNetworkParametersRequest{})
+	execClient, err := client.NewExecutionDataClient(accessURL, chain)
 	if err != nil {
-		log.Fatalf("could not get network parameters: %v", err)
+		log.Fatalf("could not create execution data client: %v", err)
+	}
+
+	t := &Tracker{
+		accessClient: access.NewAccessAPIClient(conn),
+		execClient:   execClient,
 	}
-	t.chain = flow.ChainID(resp.ChainId).Chain()
 
 	err = t.FollowBlocks(ctx)
 	if err != nil {
@@ -68,7 +64,7 @@ func (t *Tracker) FollowBlocks(ctx context.Context) error {
 		log.Fatalf("could not get latest block header: %v", err)
 	}
 
-	lastHeight := header.Block.Height
+	height := header.Block.Height + 1
 
 	for {
 		select {
@@ -77,57 +73,39 @@ func (t *Tracker) FollowBlocks(ctx context.Context) error {
 		default:
 		}
 
-		// get the next block, blocking until it's available
-		header, err := t.accessClient.GetBlockHeaderByHeight(ctx, &access.GetBlockHeaderByHeightRequest{Height: lastHeight + 1})
+		// get the next block's execution data, blocking until it's available
+		execData, err := t.execClient.GetExecutionDataByHeight(ctx, height)
 		if err != nil {
-			if status.Code(err) == codes.NotFound {
+			if client.IsNotAvailable(err) {
 				time.Sleep(500 * time.Millisecond)
 				continue
 			}
-
-			return fmt.Errorf("could not get block header for height %d: %w", lastHeight+1, err)
+			return fmt.Errorf("could not get execution data for height %d: %w", height, err)
 		}
 
-		lastHeight = header.Block.Height
-
-		log.Printf("%d: %x", header.Block.Height, header.Block.Id)
-
-		var accounts []flow.Address
-		for {
-			resp, err := t.execClient.GetExecutionDataByBlockID(ctx, &executiondata.GetExecutionDataByBlockIDRequest{BlockId: header.Block.Id})
-			if err != nil {
-				if status.Code(err) == codes.NotFound || strings.Contains(err.Error(), "not found") {
-					time.Sleep(500 * time.Millisecond)
-					continue
-				}
-				return fmt.Errorf("could not get execution data: %w", err)
-			}
-
-			accounts, err = getModifiedAccounts(resp.GetBlockExecutionData(), t.chain)
-			if err != nil {
-				return fmt.Errorf("failed to get execution data: %w", err)
-			}
+		log.Printf("%d: %x", height, execData.BlockID)
 
-			break
+		accounts, err := getModifiedAccounts(execData)
+		if err != nil {
+			return fmt.Errorf("failed to get execution data: %w", err)
 		}
 
 		log.Printf("modified accounts: %d", len(accounts))
 		// for _, address := range accounts {
 		// 	fmt.Printf("0x%s\n", address)
 		// }
-		time.Sleep(800 * time.Millisecond)
-	}
-}
 
-func getModifiedAccounts(executionData *entities.BlockExecutionData, chain flow.Chain) ([]flow.Address, error) {
-	updates, err := extractTrieUpdates(executionData, chain)
-	if err != nil {
-		return nil, fmt.Errorf("could not convert execution data: %w", err)
+		height++
 	}
+}
 
+func getModifiedAccounts(executionData *execution_data.BlockExecutionData) ([]flow.Address, error) {
 	accounts := map[flow.Address]struct{}{}
-	for _, update := range updates {
-		for _, payload := range update.Payloads {
+	for _, chunk := range executionData.ChunkExecutionDatas {
+		if chunk.TrieUpdate == nil {
+			continue
+		}
+		for _, payload := range chunk.TrieUpdate.Payloads {
 			key, err := payload.Key()
 			if err != nil {
 				return nil, fmt.Errorf("could not get key: %w", err)
@@ -145,23 +123,3 @@ func getModifiedAccounts(executionData *entities.BlockExecutionData, chain flow.
 
 	return addresses, nil
 }
-
-func extractTrieUpdates(m *entities.BlockExecutionData, chain flow.Chain) ([]*ledger.TrieUpdate, error) {
-	if m == nil {
-		return nil, convert.ErrEmptyMessage
-	}
-
-	updates := []*ledger.TrieUpdate{}
-	for i, c := range m.GetChunkExecutionData() {
-		chunk, err := convert.MessageToChunkExecutionData(c, chain)
-		if err != nil {
-			return nil, fmt.Errorf("could not convert chunk %d: %w", i, err)
-		}
-
-		if chunk.TrieUpdate != nil {
-			updates = append(updates, chunk.TrieUpdate)
-		}
-	}
-
-	return updates, nil
-}